@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// edge joins two egos, by their index, close enough to be connected
+type edge struct {
+	a, b     int // a comes before b
+	distance float64
+}
+
+// grid buckets the egos into square cells as wide as the farthest
+// distance anything is connected at, so that every ego only needs
+// to be compared with the ones in its own cell and the eight around it
+type grid struct {
+	cell  float64
+	cells map[[2]int][]int
+}
+
+func newGrid(egos []ego, cell float64) grid {
+	g := grid{cell: cell, cells: map[[2]int][]int{}}
+	for i := range egos {
+		key := g.key(egos[i].X, egos[i].Y)
+		g.cells[key] = append(g.cells[key], i)
+	}
+	return g
+}
+
+func (g grid) key(x, y float64) [2]int {
+	return [2]int{int(math.Floor(x / g.cell)), int(math.Floor(y / g.cell))}
+}
+
+// edges returns every pair of egos no farther apart than the given
+// distance, which must be no more than the cell size, once each
+// and in order, by the first ego and then the second
+func (g grid) edges(egos []ego, within float64) []edge {
+	edges := []edge{}
+	near := []int{}
+	for i := range egos {
+		e := &egos[i]
+		k := g.key(e.X, e.Y)
+
+		near = near[:0]
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range g.cells[[2]int{k[0] + dx, k[1] + dy}] {
+					// The pair comes up again from the other side
+					if j > i {
+						near = append(near, j)
+					}
+				}
+			}
+		}
+
+		sort.Ints(near)
+		for _, j := range near {
+			if distance := e.distanceTo(&egos[j]); distance <= within {
+				edges = append(edges, edge{i, j, distance})
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// referenceEdges compares every ego with every other one,
+// the way the first version of the sketch did, keeping each pair once
+func referenceEdges(egos []ego, within float64) []edge {
+	edges := []edge{}
+	for n := range egos {
+		for m := n + 1; m < len(egos); m++ {
+			if distance := egos[n].distanceTo(&egos[m]); distance <= within {
+				edges = append(edges, edge{n, m, distance})
+			}
+		}
+	}
+	return edges
+}
+
+// travelling sets off egos the way the sketch does, a few steps along
+func travelling(count int, s float64) []ego {
+	egos := make([]ego, count)
+	for i := range egos {
+		egos[i].init(s/2, s/2, s*0.075, s*0.85/2, 0.05+rand.Float64()*0.55)
+		for step := 0; step < rand.Intn(100); step++ {
+			egos[i].travel()
+		}
+	}
+	return egos
+}
+
+func TestGridMatchesReference(t *testing.T) {
+	rand.Seed(1)
+	const s = 1000.0
+
+	for _, count := range []int{0, 1, 2, 30, 500, 2000} {
+		egos := travelling(count, s)
+		for _, cell := range []float64{s * 0.01, s * 0.2, s} {
+			for _, within := range []float64{0, cell / 2, cell} {
+				got := newGrid(egos, cell).edges(egos, within)
+				want := referenceEdges(egos, within)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%d egos, cell %v, within %v: %d edges, want %d", count, cell, within, len(got), len(want))
+				}
+			}
+		}
+	}
+}
+
+// Egos right on the lines between cells, exactly the distance apart,
+// on top of each other, and on either side of zero
+func TestGridEdgeCases(t *testing.T) {
+	const cell = 10.0
+	egos := []ego{}
+	for x := -2.0; x <= 2; x++ {
+		for y := -2.0; y <= 2; y++ {
+			egos = append(egos, ego{X: x * cell, Y: y * cell})
+		}
+	}
+	egos = append(egos, ego{X: 0, Y: 0}, ego{X: cell / 2, Y: -cell / 2}, ego{X: -0.0001, Y: cell - 0.0001})
+
+	for _, within := range []float64{0, cell / 2, cell} {
+		got := newGrid(egos, cell).edges(egos, within)
+		want := referenceEdges(egos, within)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("within %v: got %v, want %v", within, got, want)
+		}
+	}
+}
+
+func BenchmarkGrid(b *testing.B) {
+	rand.Seed(1)
+	egos := travelling(2000, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newGrid(egos, 50).edges(egos, 50)
+	}
+}
+
+func BenchmarkReference(b *testing.B) {
+	rand.Seed(1)
+	egos := travelling(2000, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		referenceEdges(egos, 50)
+	}
+}
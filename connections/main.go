@@ -4,17 +4,21 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"log/slog"
 	"math"
 	"math/rand"
+	"os"
 
-	stackblur "github.com/esimov/stackblur-go"
+	"github.com/dangelov/martegeno/layers"
+	"github.com/dangelov/martegeno/orbits"
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
-	"github.com/lucasb-eyer/go-colorful"
 )
 
 type ego struct {
 	X, Y, xOffset, yOffset, Radius, Angle, Speed float64
 	Flick                                        bool
+	orbit                                        orbits.Orbit
 }
 
 func (e *ego) init(xOffset, yOffset, minRadius, maxRadius float64, speed float64) {
@@ -36,15 +40,23 @@ func (e *ego) init(xOffset, yOffset, minRadius, maxRadius float64, speed float64
 	// A random flicker phase prevents having
 	// all egos flick at the same time like a strobe
 	e.Flick = rand.Int31n(10) < 5
+
+	e.orbit = orbits.Circle(e.Radius)
 }
 
 func (e *ego) travel() {
 	// Move the angle forward
 	e.Angle += e.Speed
 	// Adjust the X, Y coordinates
-	sin, cos := math.Sincos(gg.Radians(e.Angle))
-	e.X = e.Radius*cos + e.xOffset
-	e.Y = e.Radius*sin + e.yOffset
+	x, y := e.orbit.At(e.Angle)
+	e.X = x + e.xOffset
+	e.Y = y + e.yOffset
+}
+
+// flicking is whether the ego's flicked on in the given frame, every
+// other one from its phase, so an even number of frames loops
+func (e *ego) flicking(frame int) bool {
+	return e.Flick != (frame%2 == 1)
 }
 
 func (e *ego) distanceTo(e2 *ego) float64 {
@@ -91,7 +103,35 @@ func main() {
 		strokeWidth   = s / 150
 		glowThickness = strokeWidth * 4
 		glowRadius    = glowThickness / 2
+		count         = 30  // How many egos will be travelling
+		frames        = 360 // How many frames the animation takes
+		// Every ego goes around whole times, so the animation loops without
+		// a jump. Slow egos need a lot of frames to go around at a speed
+		// of their own, or else they go around from once up to loopTurns times.
+		loop      = false
+		loopTurns = 3
+		// Egos pull on each other, and on a mass at the center,
+		// starting from their orbits but going wherever they're pulled
+		physics     = false
+		gravity     = 5.0     // How hard they pull, for the size of the image
+		softening   = s / 100 // How close they get before the pull eases off
+		centralMass = 100.0   // Of the center, where every ego weighs 1
+		merge       = true    // Whether egos that run into each other become one
+		// Which egos connect: "distance" for all of them closer than M,
+		// or "nearest", "delaunay", "gabriel", "relative" or "spanning"
+		connect = "distance"
+		k       = 3 // Neighbours every ego connects to, when nearest
+		// JSON file with curves for how lines look, over the ones below
+		stylePath = ""
+		// How the glow mixes with the lines: "normal", "add", "screen",
+		// "multiply" or "overlay"
+		glowBlend   = "normal"
+		glowOpacity = 1.0
+
+		logLevel  = slog.LevelInfo // slog.LevelDebug for merges and stats on every frame, slog.LevelWarn for less
+		statsPath = ""             // File for the stats of every frame, as lines of JSON, if any
 	)
+	report.Log(logLevel)
 
 	// String-based seed for the random generator
 	// is easier to remember between experiments
@@ -102,107 +142,162 @@ func main() {
 	}
 	rand.Seed(seedNumerical)
 
-	// How many egos will be travelling?
-	egos := [30]ego{}
+	// Lines fade out from N to M, blend from one colour to the other
+	// from N to B, glow from B in and flick within V
+	st := style{
+		Opacity: curve{Near: distanceN, Far: distanceM, From: 1, To: 0},
+		Color: gradient{
+			Stops: []string{"#F72F4D", "#D6FF32"},
+			Curve: curve{Near: distanceB, Far: distanceN, From: 1, To: 0},
+		},
+		Width:   curve{From: strokeWidth, To: strokeWidth},
+		Glow:    curve{Near: distanceV, Far: distanceB, From: glowThickness, To: 0},
+		Flicker: curve{Near: distanceV, Far: distanceV, From: 1, To: 0},
+	}
+	if stylePath != "" {
+		var err error
+		if st, err = loadStyle(stylePath, st); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := st.prepare(); err != nil {
+		log.Fatal(err)
+	}
+
+	glow, ok := layers.Blends[glowBlend]
+	if !ok {
+		log.Fatalf("no blend mode named %q", glowBlend)
+	}
+
+	connections, ok := topologies(distanceM, k)[connect]
+	if !ok {
+		log.Fatalf("no topology named %q", connect)
+	}
+
+	// Every ego takes the next kind of orbit along: circle,
+	// ellipse, kepler, precessing or epicycle
+	kinds := []string{"circle"}
+	if loop && physics {
+		log.Fatal("egos pulled about by gravity never come back around, so they can't loop")
+	}
+
+	egos := make([]ego, count)
 
 	// Initialize all of them
 	for i := range egos {
 		speed := minSpeed + rand.Float64()*(maxSpeed-minSpeed)
 		egos[i] = ego{}
 		egos[i].init(s/2, s/2, minRadius, maxRadius, speed)
-	}
 
-	// Rotate all the egos until we complete a circle,
-	// and then, for each rotation...
-	for i := 0; i < 360; i++ {
-		// Drawing context for the connecting lines
-		lc := gg.NewContext(int(s), int(s))
-		// Drawing context for the glow
-		gc := gg.NewContext(int(s), int(s))
-		// Drawing context for the egos
-		ec := gg.NewContext(int(s), int(s))
-		// Composite context
-		cc := gg.NewContext(int(s), int(s))
-
-		clr, err := colorful.Hex("#F72F4D")
+		o, err := orbits.New(kinds[i%len(kinds)], egos[i].Radius)
 		if err != nil {
 			log.Fatal(err)
 		}
-		clr2, err := colorful.Hex("#D6FF32")
+		egos[i].orbit = o
+	}
+
+	if loop {
+		paths, speeds := make([]orbits.Orbit, count), make([]float64, count)
+		for i := range egos {
+			paths[i], speeds[i] = egos[i].orbit, egos[i].Speed
+		}
+		speeds, err := orbits.LoopSpeeds(paths, speeds, frames, loopTurns)
 		if err != nil {
 			log.Fatal(err)
 		}
+		for i := range egos {
+			egos[i].Speed = speeds[i]
+		}
+	}
 
-		// Draw all the ego connections
+	// Once pulled off their orbits, the egos go wherever they're pulled
+	var world *orbits.Nbody
+	if physics {
+		world = orbits.NewNbody(orbits.NewPhysics(gravity, softening, centralMass, merge))
 		for n := range egos {
-			ego := &egos[n]
-			ego.travel()
-
-			// Draw the line between this and all the other egos
-			for m := range egos {
-				if m == n { // Don't do anything with itself
-					continue
-				}
-
-				ego2 := &egos[m]
-
-				distance := ego.distanceTo(ego2)
-
-				fmt.Printf("distance %f", distance)
-
-				// Too far, don't draw a line
-				if distance > distanceM {
-					println(" not drawn")
-					continue
-				}
+			x, y := egos[n].orbit.At(egos[n].Angle)
+			world.Add(x, y, egoSize)
+		}
+		world.Launch()
+	}
 
-				// Set opacity based on distance between M and N
-				opacity := 1.0 - ((distance - distanceN) / (distanceM - distanceN))
-				opacity = clampFloat(opacity, 0, 1.0)
-				fmt.Printf(" opacity: %f", opacity)
-
-				// Blend two colors based on distance betwee N and B
-				midpoint := 1 - ((distance - distanceB) / (distanceN - distanceB))
-				midpoint = clampFloat(midpoint, 0, 1.0)
-				fmt.Printf(" midpoint: %f", midpoint)
-
-				blended := clr.BlendHcl(clr2, midpoint).Clamped()
-
-				// Create a new color based on our blend, but this time
-				// with a custom Alpha opacity
-				r, g, b, _ := blended.RGBA()
-				a := uint8(opacity * 255)
-				fmt.Printf(" a: %d", a)
-				finalColor := color.NRGBA{uint8(r), uint8(g), uint8(b), a}
-
-				// Super short distances should start flicking
-				if distance < distanceV {
-					ego.Flick = !ego.Flick
-					if ego.Flick {
-						a = 127
-					}
-					finalColor = color.NRGBA{uint8(r), uint8(g), uint8(b), a}
-				}
+	var stats *statsWriter
+	if statsPath != "" {
+		f, err := os.Create(statsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		stats = newStatsWriter(f)
+	}
 
-				if distance < distanceB {
-					// Blend two colors based on distance betwee N and B
-					thickness := 1 - ((distance - distanceV) / (distanceB - distanceV))
-					thickness = clampFloat(thickness, 0, 1.0)
-					fmt.Printf(" thickness: %f", midpoint)
+	// Rotate all the egos until we complete a circle,
+	// and then, for each rotation...
+	progress := report.NewProgress(frames)
+	for i := 0; i < frames; i++ {
+		// Over a black background, the lines at the bottom,
+		// on top of that the glow, and finally the egos
+		ls := layers.New(int(s), int(s), color.RGBA{0, 0, 0, 255})
+		lc := ls.Add("lines", 1, layers.Normal)
+		gc := ls.Add("glow", glowOpacity, glow, layers.Blur(uint32(math.Floor(glowRadius))))
+		ec := ls.Add("egos", 1, layers.Normal)
+
+		// Move all the egos
+		if world != nil {
+			for _, gone := range world.Step(i) {
+				egos = append(egos[:gone], egos[gone+1:]...)
+			}
+			for n := range egos {
+				x, y := world.At(n)
+				egos[n].X, egos[n].Y = x+egos[n].xOffset, y+egos[n].yOffset
+			}
+		} else {
+			for n := range egos {
+				egos[n].travel()
+			}
+		}
 
-					gc.DrawLine(ego.X, ego.Y, ego2.X, ego2.Y)
-					gc.SetColor(finalColor)
-					gc.SetLineWidth(glowThickness * thickness)
-					gc.Stroke()
+		// Draw the line between every pair of connected egos
+		edges := connections(egos)
+		frame := newFrameStats(i, edges)
+		for _, e := range edges {
+			ego, ego2 := &egos[e.a], &egos[e.b]
+			distance := e.distance
+
+			opacity := clampFloat(st.Opacity.at(distance), 0, 1.0)
+
+			// Create a new color from the gradient, but this time
+			// with a custom Alpha opacity
+			r, g, b := st.Color.at(distance).RGB255()
+			a := uint8(opacity * 255)
+
+			// Super short distances should start flicking,
+			// both egos at either end
+			if flicker := clampFloat(st.Flicker.at(distance), 0, 1.0); flicker > 0 {
+				frame.Flickering++
+				if ego.flicking(i) {
+					a = uint8(float64(a) + (127-float64(a))*flicker)
 				}
+			}
+			finalColor := color.NRGBA{r, g, b, a}
+
+			if glow := st.Glow.at(distance); glow > 0 {
+				frame.Glowing++
+				gc.DrawLine(ego.X, ego.Y, ego2.X, ego2.Y)
+				gc.SetColor(finalColor)
+				gc.SetLineWidth(glow)
+				gc.Stroke()
+			}
 
-				lc.DrawLine(ego.X, ego.Y, ego2.X, ego2.Y)
-				lc.SetColor(finalColor)
-				lc.SetLineWidth(strokeWidth)
-				lc.Stroke()
-
-				println("")
+			if dash := st.Dash.at(distance); dash > 0 {
+				lc.SetDash(dash, dash)
+			} else {
+				lc.SetDash()
 			}
+			lc.DrawLine(ego.X, ego.Y, ego2.X, ego2.Y)
+			lc.SetColor(finalColor)
+			lc.SetLineWidth(st.Width.at(distance))
+			lc.Stroke()
 		}
 
 		// Draw all the egos
@@ -215,18 +310,17 @@ func main() {
 			ec.Fill()
 		}
 
-		// Set a background color
-		cc.SetColor(color.RGBA{0, 0, 0, 255})
-		cc.Clear()
-
-		// Bottom layer: the lines
-		cc.DrawImage(lc.Image(), 0, 0)
-		// On top of that, the glow
-		cc.DrawImage(stackblur.Process(gc.Image(), uint32(math.Floor(glowRadius))), 0, 0)
-		// And finally, the egos
-		cc.DrawImage(ec.Image(), 0, 0)
-
 		// Save the output
-		cc.SavePNG(fmt.Sprintf("i-%d.png", i))
+		if err := gg.SavePNG(fmt.Sprintf("i-%d.png", i), ls.Composite()); err != nil {
+			log.Fatal(err)
+		}
+
+		slog.Debug("frame", "stats", frame)
+		if stats != nil {
+			if err := stats.write(frame); err != nil {
+				log.Fatal(err)
+			}
+		}
+		progress.Frame()
 	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/dangelov/martegeno/orbits"
+)
+
+func TestLoopComesBackAround(t *testing.T) {
+	rand.Seed(7)
+	const frames, s = 360, 1000.0
+
+	// As slow as the sketch's egos, which would all go around once
+	egos := travelling(30, s)
+	paths, speeds := make([]orbits.Orbit, len(egos)), make([]float64, len(egos))
+	for i := range egos {
+		paths[i], speeds[i] = egos[i].orbit, egos[i].Speed
+	}
+	speeds, err := orbits.LoopSpeeds(paths, speeds, frames, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turns := map[float64]bool{}
+	for i := range egos {
+		egos[i].Speed = speeds[i]
+		turns[math.Round(speeds[i]*frames/360)] = true
+	}
+	if len(turns) < 2 {
+		t.Errorf("every ego goes around %v times, turning together like a wheel", turns)
+	}
+
+	// Everything's the same the frame after the last as in the first
+	first := append([]ego{}, egos...)
+	for i := range first {
+		first[i].travel()
+	}
+	for f := 0; f <= frames; f++ {
+		for i := range egos {
+			egos[i].travel()
+		}
+	}
+	for i := range egos {
+		if d := egos[i].distanceTo(&first[i]); d > 1e-6 {
+			t.Errorf("ego %d is %v off after %d frames", i, d, frames)
+		}
+		if egos[i].flicking(frames) != egos[i].flicking(0) {
+			t.Errorf("ego %d flicks differently after %d frames", i, frames)
+		}
+		// And flicks on and off from one frame to the next
+		if egos[i].flicking(1) == egos[i].flicking(0) {
+			t.Errorf("ego %d stays flicked the same way", i)
+		}
+	}
+}
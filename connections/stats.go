@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// frameStats sums up the connections drawn in a frame
+type frameStats struct {
+	Frame        int     `json:"frame"`
+	Edges        int     `json:"edges"`
+	MeanDistance float64 `json:"meanDistance"`
+	Shortest     float64 `json:"shortest"`
+	Longest      float64 `json:"longest"`
+	Glowing      int     `json:"glowing"`
+	Flickering   int     `json:"flickering"`
+}
+
+func newFrameStats(frame int, edges []edge) frameStats {
+	st := frameStats{Frame: frame, Edges: len(edges)}
+	if len(edges) == 0 {
+		return st
+	}
+
+	st.Shortest = math.Inf(1)
+	for _, e := range edges {
+		st.MeanDistance += e.distance
+		st.Shortest = math.Min(st.Shortest, e.distance)
+		st.Longest = math.Max(st.Longest, e.distance)
+	}
+	st.MeanDistance /= float64(len(edges))
+	return st
+}
+
+// statsWriter writes the stats of every frame as a line of JSON
+type statsWriter struct {
+	enc *json.Encoder
+}
+
+func newStatsWriter(w io.Writer) *statsWriter {
+	return &statsWriter{json.NewEncoder(w)}
+}
+
+func (sw *statsWriter) write(st frameStats) error {
+	return sw.enc.Encode(st)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// easing shapes how a value changes from one end of a curve to the other,
+// with t going from 0 to 1
+type easing func(t float64) float64
+
+var easings = map[string]easing{
+	"linear": func(t float64) float64 { return t },
+	"in":     func(t float64) float64 { return t * t },
+	"out":    func(t float64) float64 { return t * (2 - t) },
+	"in-out": func(t float64) float64 { return t * t * (3 - 2*t) },
+	"sine":   func(t float64) float64 { return (1 - math.Cos(t*math.Pi)) / 2 },
+}
+
+// curve maps the distance between two egos to a value: From when they're
+// Near or closer, To when they're Far or farther, and eased in between.
+// When Near and Far are the same, the value steps from one to the other there.
+type curve struct {
+	Near   float64 `json:"near"`
+	Far    float64 `json:"far"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Easing string  `json:"easing"` // Linear when empty
+}
+
+func (c curve) at(distance float64) float64 {
+	if distance < c.Near {
+		return c.From
+	}
+	if distance >= c.Far {
+		return c.To
+	}
+
+	ease := easings["linear"]
+	if c.Easing != "" {
+		ease = easings[c.Easing]
+	}
+	t := ease((distance - c.Near) / (c.Far - c.Near))
+	return c.From + (c.To-c.From)*t
+}
+
+// gradient blends through its colours in HCL, as the curve goes from 0 to 1
+type gradient struct {
+	Stops []string `json:"stops"` // Colours in hex
+	Curve curve    `json:"curve"`
+
+	colors []colorful.Color
+}
+
+func (g gradient) at(distance float64) colorful.Color {
+	if len(g.colors) == 1 {
+		return g.colors[0]
+	}
+	t := clampFloat(g.Curve.at(distance), 0, 1) * float64(len(g.colors)-1)
+	i := int(math.Min(math.Floor(t), float64(len(g.colors)-2)))
+	return g.colors[i].BlendHcl(g.colors[i+1], t-float64(i)).Clamped()
+}
+
+// style is how connections look, from how far apart their egos are.
+// It loads from JSON, where any of the curves can be left out, like:
+//
+//	{"opacity": {"near": 50, "far": 300, "from": 1, "to": 0, "easing": "in-out"},
+//	 "color": {"stops": ["#0B486B", "#CFF09E"], "curve": {"near": 0, "far": 200, "from": 1, "to": 0}}}
+type style struct {
+	Opacity curve    `json:"opacity"`
+	Color   gradient `json:"color"`
+	Width   curve    `json:"width"`
+	Glow    curve    `json:"glow"`    // Width of the glow, none when 0
+	Dash    curve    `json:"dash"`    // Length of the dashes, solid when 0
+	Flicker curve    `json:"flicker"` // How far flicked lines fade to half their opacity
+}
+
+// loadStyle reads the curves in the file over the ones in the style
+func loadStyle(path string, st style) (style, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("reading style %s: %w", path, err)
+	}
+	return st, nil
+}
+
+// prepare checks the style, and parses its colours
+func (st *style) prepare() error {
+	for _, c := range []curve{st.Opacity, st.Color.Curve, st.Width, st.Glow, st.Dash, st.Flicker} {
+		if _, ok := easings[c.Easing]; c.Easing != "" && !ok {
+			return fmt.Errorf("no easing named %q", c.Easing)
+		}
+	}
+
+	if len(st.Color.Stops) == 0 {
+		return fmt.Errorf("the color gradient needs at least one stop")
+	}
+	st.Color.colors = nil
+	for _, hex := range st.Color.Stops {
+		c, err := colorful.Hex(hex)
+		if err != nil {
+			return err
+		}
+		st.Color.colors = append(st.Color.colors, c)
+	}
+	return nil
+}
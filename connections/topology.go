@@ -0,0 +1,284 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// topology picks which egos are connected, from where they are now
+type topology func(egos []ego) []edge
+
+// topologies returns every topology by name. Egos connect when they're
+// no farther apart than within, or to their k nearest neighbours.
+func topologies(within float64, k int) map[string]topology {
+	return map[string]topology{
+		"distance": func(egos []ego) []edge { return newGrid(egos, within).edges(egos, within) },
+		"nearest":  nearest(k),
+		"delaunay": delaunay,
+		"gabriel":  gabriel,
+		"relative": relativeNeighbourhood,
+		"spanning": spanningTree,
+	}
+}
+
+// sortEdges puts edges in order, by the first ego and then the second,
+// so they're drawn the same way every time
+func sortEdges(edges []edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].a != edges[j].a {
+			return edges[i].a < edges[j].a
+		}
+		return edges[i].b < edges[j].b
+	})
+}
+
+// join returns the edge between two egos, the first one first
+func join(egos []ego, a, b int) edge {
+	if a > b {
+		a, b = b, a
+	}
+	return edge{a, b, egos[a].distanceTo(&egos[b])}
+}
+
+// nearest connects every ego with the k others closest to it.
+// Egos can end up with more, when they're close to others in turn.
+func nearest(k int) topology {
+	return func(egos []ego) []edge {
+		seen := map[[2]int]bool{}
+		edges := []edge{}
+		for i := range egos {
+			// The closest so far, nearest first
+			closest := []edge{}
+			for j := range egos {
+				if j == i {
+					continue
+				}
+				e := join(egos, i, j)
+				at := sort.Search(len(closest), func(n int) bool { return closest[n].distance > e.distance })
+				if at >= k {
+					continue
+				}
+				closest = append(closest, edge{})
+				copy(closest[at+1:], closest[at:])
+				closest[at] = e
+				if len(closest) > k {
+					closest = closest[:k]
+				}
+			}
+
+			for _, e := range closest {
+				if !seen[[2]int{e.a, e.b}] {
+					seen[[2]int{e.a, e.b}] = true
+					edges = append(edges, e)
+				}
+			}
+		}
+		sortEdges(edges)
+		return edges
+	}
+}
+
+// triangle of egos, with the circle through all three of them
+type triangle struct {
+	v          [3]int
+	cx, cy, r2 float64 // Center and squared radius of the circle
+}
+
+func newTriangle(points [][2]float64, a, b, c int) triangle {
+	ax, ay := points[a][0], points[a][1]
+	bx, by := points[b][0], points[b][1]
+	cx, cy := points[c][0], points[c][1]
+
+	d := 2 * (ax*(by-cy) + bx*(cy-ay) + cx*(ay-by))
+	ux := ((ax*ax+ay*ay)*(by-cy) + (bx*bx+by*by)*(cy-ay) + (cx*cx+cy*cy)*(ay-by)) / d
+	uy := ((ax*ax+ay*ay)*(cx-bx) + (bx*bx+by*by)*(ax-cx) + (cx*cx+cy*cy)*(bx-ax)) / d
+	return triangle{v: [3]int{a, b, c}, cx: ux, cy: uy, r2: (ax-ux)*(ax-ux) + (ay-uy)*(ay-uy)}
+}
+
+// delaunay connects the egos into triangles, none of which has
+// another ego inside the circle through its corners, adding the egos
+// one at a time into a triangle large enough to hold all of them
+func delaunay(egos []ego) []edge {
+	if len(egos) < 2 {
+		return []edge{}
+	}
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	points := make([][2]float64, len(egos), len(egos)+3)
+	for i, e := range egos {
+		points[i] = [2]float64{e.X, e.Y}
+		minX, maxX = math.Min(minX, e.X), math.Max(maxX, e.X)
+		minY, maxY = math.Min(minY, e.Y), math.Max(maxY, e.Y)
+	}
+
+	// The corners of the first triangle are so far away that
+	// no circle through them reaches between the egos themselves
+	midX, midY := (minX+maxX)/2, (minY+maxY)/2
+	span := math.Max(math.Max(maxX-minX, maxY-minY), 1) * 1000
+	super := len(points)
+	points = append(points, [2]float64{midX - span, midY - span}, [2]float64{midX + span, midY - span}, [2]float64{midX, midY + span})
+	triangles := []triangle{newTriangle(points, super, super+1, super+2)}
+
+	// Egos on top of each other would make triangles with no area,
+	// so only the first of them goes in, and the others join it after
+	first := map[[2]float64]int{}
+	copies := []edge{}
+	for i, p := range points[:super] {
+		if f, ok := first[p]; ok {
+			copies = append(copies, edge{f, i, 0})
+			continue
+		}
+		first[p] = i
+
+		// Every triangle whose circle the ego falls in goes, leaving a hole
+		// to be filled with new triangles from its sides to the ego
+		sides := map[[2]int]int{}
+		kept := triangles[:0]
+		for _, t := range triangles {
+			if (p[0]-t.cx)*(p[0]-t.cx)+(p[1]-t.cy)*(p[1]-t.cy) >= t.r2 {
+				kept = append(kept, t)
+				continue
+			}
+			for n := 0; n < 3; n++ {
+				a, b := t.v[n], t.v[(n+1)%3]
+				if a > b {
+					a, b = b, a
+				}
+				sides[[2]int{a, b}]++
+			}
+		}
+		triangles = kept
+
+		for side, count := range sides {
+			// Sides shared by two of the triangles are inside the hole
+			if count == 1 {
+				triangles = append(triangles, newTriangle(points, side[0], side[1], i))
+			}
+		}
+	}
+
+	seen := map[[2]int]bool{}
+	edges := []edge{}
+	for _, t := range triangles {
+		for n := 0; n < 3; n++ {
+			a, b := t.v[n], t.v[(n+1)%3]
+			if a >= super || b >= super {
+				continue
+			}
+			e := join(egos, a, b)
+			if !seen[[2]int{e.a, e.b}] {
+				seen[[2]int{e.a, e.b}] = true
+				edges = append(edges, e)
+			}
+		}
+	}
+	edges = append(edges, copies...)
+	sortEdges(edges)
+	return edges
+}
+
+// emptyBetween keeps the edges with no other ego in the area between
+// their ends. Both graphs that use it only ever keep Delaunay edges,
+// so those are the only ones that need checking, along with the same
+// edges from every other ego on top of either end, as an ego right on
+// top of one end is never inside the area.
+func emptyBetween(egos []ego, inside func(a, b, p *ego, distance float64) bool) []edge {
+	copies := map[[2]float64][]int{}
+	for i, e := range egos {
+		p := [2]float64{e.X, e.Y}
+		copies[p] = append(copies[p], i)
+	}
+	at := func(i int) []int { return copies[[2]float64{egos[i].X, egos[i].Y}] }
+
+	candidates := []edge{}
+	for _, e := range delaunay(egos) {
+		if e.distance == 0 {
+			// Only ever between the first of some egos on top of each
+			// other and one of the rest, which all join up in turn
+			if group := at(e.a); e.a == group[0] && e.b == group[1] {
+				for n, a := range group {
+					for _, b := range group[n+1:] {
+						candidates = append(candidates, edge{a, b, 0})
+					}
+				}
+			}
+			continue
+		}
+		for _, a := range at(e.a) {
+			for _, b := range at(e.b) {
+				candidates = append(candidates, join(egos, a, b))
+			}
+		}
+	}
+
+	edges := []edge{}
+	for _, e := range candidates {
+		a, b := &egos[e.a], &egos[e.b]
+		empty := true
+		for n := range egos {
+			if n != e.a && n != e.b && inside(a, b, &egos[n], e.distance) {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			edges = append(edges, e)
+		}
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// inCircle is whether p is inside the circle with a and b on either side
+func inCircle(a, b, p *ego, distance float64) bool {
+	mid := ego{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	return mid.distanceTo(p) < distance/2
+}
+
+// inLune is whether p is closer to both a and b than they are to each other
+func inLune(a, b, p *ego, distance float64) bool {
+	return a.distanceTo(p) < distance && b.distanceTo(p) < distance
+}
+
+// gabriel connects egos with no other ego in the circle between them
+func gabriel(egos []ego) []edge {
+	return emptyBetween(egos, inCircle)
+}
+
+// relativeNeighbourhood connects egos with no other ego closer to
+// both of them than they are to each other
+func relativeNeighbourhood(egos []ego) []edge {
+	return emptyBetween(egos, inLune)
+}
+
+// spanningTree connects all the egos with the shortest lines in total,
+// joining up the closest of them first, which are all Delaunay edges
+func spanningTree(egos []ego) []edge {
+	candidates := delaunay(egos)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	// Every ego points towards the first of the egos joined up with it
+	parent := make([]int, len(egos))
+	for i := range parent {
+		parent[i] = i
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	edges := []edge{}
+	for _, e := range candidates {
+		ra, rb := root(e.a), root(e.b)
+		if ra == rb {
+			continue
+		}
+		parent[rb] = ra
+		edges = append(edges, e)
+	}
+	sortEdges(edges)
+	return edges
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// bruteEmptyBetween compares every pair of egos with every other ego
+func bruteEmptyBetween(egos []ego, inside func(a, b, p *ego, distance float64) bool) []edge {
+	edges := []edge{}
+	for i := range egos {
+		for j := i + 1; j < len(egos); j++ {
+			e := join(egos, i, j)
+			empty := true
+			for n := range egos {
+				if n != i && n != j && inside(&egos[i], &egos[j], &egos[n], e.distance) {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+// bruteDelaunay keeps the sides of every triangle with no other ego
+// inside the circle through its corners. With no four egos on a circle,
+// that's the one and only Delaunay triangulation.
+func bruteDelaunay(egos []ego) []edge {
+	// Two egos make no triangle, but they're still joined
+	if len(egos) == 2 {
+		return []edge{join(egos, 0, 1)}
+	}
+	points := make([][2]float64, len(egos))
+	for i, e := range egos {
+		points[i] = [2]float64{e.X, e.Y}
+	}
+	seen := map[[2]int]bool{}
+	edges := []edge{}
+	for i := range egos {
+		for j := i + 1; j < len(egos); j++ {
+			for k := j + 1; k < len(egos); k++ {
+				t := newTriangle(points, i, j, k)
+				empty := true
+				for n, p := range points {
+					if n != i && n != j && n != k && (p[0]-t.cx)*(p[0]-t.cx)+(p[1]-t.cy)*(p[1]-t.cy) < t.r2 {
+						empty = false
+						break
+					}
+				}
+				if !empty {
+					continue
+				}
+				for _, pair := range [][2]int{{i, j}, {i, k}, {j, k}} {
+					if !seen[pair] {
+						seen[pair] = true
+						edges = append(edges, join(egos, pair[0], pair[1]))
+					}
+				}
+			}
+		}
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// primWeight is the total length of the shortest tree through all the egos
+func primWeight(egos []ego) float64 {
+	if len(egos) == 0 {
+		return 0
+	}
+	in := make([]bool, len(egos))
+	best := make([]float64, len(egos))
+	for i := range best {
+		best[i] = math.Inf(1)
+	}
+	best[0] = 0
+	total := 0.0
+	for range egos {
+		next := -1
+		for i := range egos {
+			if !in[i] && (next < 0 || best[i] < best[next]) {
+				next = i
+			}
+		}
+		in[next] = true
+		total += best[next]
+		for i := range egos {
+			if d := egos[next].distanceTo(&egos[i]); !in[i] && d < best[i] {
+				best[i] = d
+			}
+		}
+	}
+	return total
+}
+
+// withCopies adds egos right on top of some of the others, a few times over
+func withCopies(egos []ego) []ego {
+	if len(egos) == 0 {
+		return egos
+	}
+	copied := append([]ego{}, egos...)
+	for _, i := range []int{0, 0, len(egos) / 2, len(egos) - 1} {
+		copied = append(copied, egos[i])
+	}
+	// And one in front of the ego it copies
+	return append([]ego{egos[len(egos)-1]}, copied...)
+}
+
+// lattice puts egos on a grid, with many of them on the same circles
+func lattice(size int) []ego {
+	egos := []ego{}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			egos = append(egos, ego{X: float64(x) * 10, Y: float64(y) * 10})
+		}
+	}
+	return egos
+}
+
+func TestDelaunayMatchesBruteForce(t *testing.T) {
+	rand.Seed(2)
+	for _, count := range []int{0, 1, 2, 3, 4, 10, 30, 60} {
+		egos := travelling(count, 1000)
+		if got, want := delaunay(egos), bruteDelaunay(egos); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d egos: %d edges, want %d", count, len(got), len(want))
+		}
+	}
+}
+
+func TestDelaunayJoinsCopies(t *testing.T) {
+	rand.Seed(3)
+	egos := travelling(30, 1000)
+	copied := withCopies(egos)
+
+	edges := delaunay(copied)
+	first := map[[2]float64]int{}
+	for i, e := range copied {
+		p := [2]float64{e.X, e.Y}
+		if _, ok := first[p]; !ok {
+			first[p] = i
+		}
+	}
+
+	zero := map[[2]int]bool{}
+	for _, e := range edges {
+		if e.distance == 0 {
+			zero[[2]int{e.a, e.b}] = true
+		}
+	}
+	for i, e := range copied {
+		if f := first[[2]float64{e.X, e.Y}]; f != i && !zero[[2]int{f, i}] {
+			t.Errorf("ego %d isn't joined to %d, right under it", i, f)
+		}
+	}
+	if want := len(delaunay(egos)) + len(copied) - len(egos); len(edges) != want {
+		t.Errorf("%d edges, want %d", len(edges), want)
+	}
+}
+
+func TestGabrielAndRelativeMatchBruteForce(t *testing.T) {
+	rand.Seed(4)
+	sets := map[string][]ego{}
+	for _, count := range []int{0, 1, 2, 3, 10, 30, 100} {
+		egos := travelling(count, 1000)
+		sets[fmt.Sprintf("%d random", count)] = egos
+		sets[fmt.Sprintf("%d with copies", count)] = withCopies(egos)
+	}
+	sets["all on top of each other"] = []ego{{X: 5, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 5}}
+
+	for name, egos := range sets {
+		for _, c := range []struct {
+			name   string
+			graph  topology
+			inside func(a, b, p *ego, distance float64) bool
+		}{{"gabriel", gabriel, inCircle}, {"relative", relativeNeighbourhood, inLune}} {
+			got, want := c.graph(egos), bruteEmptyBetween(egos, c.inside)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, %s with %d egos: %d edges, want %d", c.name, name, len(egos), len(got), len(want))
+			}
+		}
+	}
+}
+
+func TestSpanningTreeIsMinimal(t *testing.T) {
+	rand.Seed(5)
+	sets := map[string][]ego{"lattice": lattice(6), "lattice copies": withCopies(lattice(4))}
+	for _, count := range []int{0, 1, 2, 3, 10, 30, 200} {
+		egos := travelling(count, 1000)
+		sets[fmt.Sprintf("%d random", count)] = egos
+		sets[fmt.Sprintf("%d with copies", count)] = withCopies(egos)
+	}
+
+	for name, egos := range sets {
+		edges := spanningTree(egos)
+		if want := len(egos) - 1; len(egos) > 0 && len(edges) != want {
+			t.Errorf("%s: %d edges for %d egos, want %d", name, len(edges), len(egos), want)
+		}
+
+		// Everything joined up, with nothing left over
+		parent := make([]int, len(egos))
+		for i := range parent {
+			parent[i] = i
+		}
+		var root func(int) int
+		root = func(i int) int {
+			if parent[i] != i {
+				parent[i] = root(parent[i])
+			}
+			return parent[i]
+		}
+		total := 0.0
+		for _, e := range edges {
+			parent[root(e.a)] = root(e.b)
+			total += e.distance
+		}
+		for i := range egos {
+			if root(i) != root(0) {
+				t.Errorf("%s: ego %d isn't joined to the others", name, i)
+				break
+			}
+		}
+
+		if want := primWeight(egos); math.Abs(total-want) > 1e-6 {
+			t.Errorf("%s: tree %v long, want %v", name, total, want)
+		}
+	}
+}
+
+func TestNearestHasEveryEgosClosest(t *testing.T) {
+	rand.Seed(6)
+	for _, count := range []int{0, 1, 2, 5, 30} {
+		egos := travelling(count, 1000)
+		for _, k := range []int{1, 3, 10} {
+			edges := nearest(k)(egos)
+			joined := map[[2]int]bool{}
+			for _, e := range edges {
+				if e.a >= e.b || joined[[2]int{e.a, e.b}] {
+					t.Fatalf("%d egos, k %d: edge %v out of order or twice", count, k, e)
+				}
+				joined[[2]int{e.a, e.b}] = true
+			}
+
+			// The k closest to every ego, by sorting all the others
+			closest := make([][]int, count)
+			for i := range egos {
+				others := []int{}
+				for j := range egos {
+					if j != i {
+						others = append(others, j)
+					}
+				}
+				sort.Slice(others, func(m, n int) bool {
+					return egos[i].distanceTo(&egos[others[m]]) < egos[i].distanceTo(&egos[others[n]])
+				})
+				if len(others) > k {
+					others = others[:k]
+				}
+				closest[i] = others
+			}
+
+			want := 0
+			for i := range egos {
+				for _, j := range closest[i] {
+					a, b := min(i, j), max(i, j)
+					if !joined[[2]int{a, b}] {
+						t.Errorf("%d egos, k %d: %d isn't joined to %d, one of its closest", count, k, i, j)
+					}
+					if i < j || !contains(closest[j], i) {
+						want++
+					}
+				}
+			}
+			if len(edges) != want {
+				t.Errorf("%d egos, k %d: %d edges, want %d", count, k, len(edges), want)
+			}
+		}
+	}
+}
+
+func contains(list []int, v int) bool {
+	for _, w := range list {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
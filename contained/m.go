@@ -2,16 +2,21 @@ package main
 
 import (
 	"io/ioutil"
+	"log"
+	"log/slog"
 	"math"
 	"math/rand"
 	"strings"
 	"unicode"
 
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
 func main() {
+	const logLevel = slog.LevelInfo // slog.LevelWarn to leave out where the image went
 	s := 1000.0
+	report.Log(logLevel)
 
 	rand.Seed(1337 * 537531)
 
@@ -55,5 +60,8 @@ func main() {
 		}
 	}
 
-	dc.SavePNG("o.png")
+	if err := dc.SavePNG("o.png"); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("saved", "path", "o.png")
 }
@@ -3,29 +3,35 @@ package main
 import (
 	"fmt"
 	"image/color"
-	"math"
+	"log"
+	"log/slog"
 	"math/rand"
 
+	"github.com/dangelov/martegeno/orbits"
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
 type ego struct {
 	X, Y, Radius, Angle, Speed float64
+	orbit                      orbits.Orbit
+	trail                      [][2]float64 // Where the ego has been, oldest first
 }
 
 func (v *ego) init(maxRadius float64, speed float64) {
 	v.Radius = float64(rand.Int31n(int32(maxRadius)))
 	v.Angle = float64(rand.Int31n(180))
 	v.Speed = speed
+	v.orbit = orbits.Circle(v.Radius)
 }
 
-func (v *ego) travel() {
+// travel moves the ego along its orbit, as far as it
+// goes in the given number of frames, or part of one
+func (v *ego) travel(frames float64) {
 	// Move the angle forward
-	v.Angle += v.Speed
+	v.Angle += v.Speed * frames
 	// Adjust the X, Y coordinates
-	sin, cos := math.Sincos(gg.Radians(v.Angle))
-	v.X = v.Radius * cos
-	v.Y = v.Radius * sin
+	v.X, v.Y = v.orbit.At(v.Angle)
 }
 
 func main() {
@@ -33,20 +39,123 @@ func main() {
 	const (
 		s         = 2000.0               // Size of final image
 		maxRadius = (s - (s * 0.15)) / 2 // Max radius of the circle around which egos travel
+		egoSize   = 12.0
+		frames    = 360   // How many frames the animation takes
+		loop      = false // Every ego goes around whole times, so the animation loops without a jump
+		loopTurns = 3     // Most times around, when they'd otherwise all go around together
+
+		// Egos pull on each other, and on a mass at the center,
+		// starting from their orbits but going wherever they're pulled
+		physics     = false
+		gravity     = 250.0  // How hard they pull, for the size of the image
+		softening   = s / 50 // How close they get before the pull eases off
+		centralMass = 100.0  // Of the center, where every ego weighs 1
+		merge       = true   // Whether egos that run into each other become one
+
+		// Egos leave trails behind them, of where they were
+		// as many frames back as the length, fading out
+		trailLength = 0        // None if 0
+		trailFade   = "linear" // "linear" or "exponential"
+		// Every frame is made of this many samples of the egos over it,
+		// so the fast ones blur into streaks
+		blurSamples = 1
+
+		logLevel = slog.LevelInfo // slog.LevelDebug for every merge, slog.LevelWarn for less
 	)
+	report.Log(logLevel)
+
+	// Every ego takes the next kind of orbit along: circle,
+	// ellipse, kepler, precessing or epicycle
+	kinds := []string{"circle"}
+	if loop && physics {
+		log.Fatal("egos pulled about by gravity never come back around, so they can't loop")
+	}
+	fade, ok := fades[trailFade]
+	if !ok {
+		log.Fatalf("no trail fade named %q", trailFade)
+	}
 
 	// How many egos will be travelling?
-	egos := [30]ego{}
+	egos := make([]ego, 30)
 
 	// Initialize all of them
 	for i := range egos {
 		egos[i] = ego{}
 		egos[i].init(maxRadius, 0.5+rand.Float64()*6.0)
+
+		o, err := orbits.New(kinds[i%len(kinds)], egos[i].Radius)
+		if err != nil {
+			log.Fatal(err)
+		}
+		egos[i].orbit = o
+	}
+
+	if loop {
+		paths, speeds := make([]orbits.Orbit, len(egos)), make([]float64, len(egos))
+		for i := range egos {
+			paths[i], speeds[i] = egos[i].orbit, egos[i].Speed
+		}
+		speeds, err := orbits.LoopSpeeds(paths, speeds, frames, loopTurns)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for i := range egos {
+			egos[i].Speed = speeds[i]
+		}
+	}
+
+	// The orbits stay the same all along, as far as
+	// every ego travels in the whole of the animation,
+	// unless they're pulled off them
+	paths := gg.NewContext(int(s), int(s))
+	var world *orbits.Nbody
+	if physics {
+		world = orbits.NewNbody(orbits.NewPhysics(gravity, softening, centralMass, merge))
+		for n := range egos {
+			egos[n].X, egos[n].Y = egos[n].orbit.At(egos[n].Angle)
+			world.Add(egos[n].X, egos[n].Y, egoSize)
+		}
+		world.Launch()
+	} else {
+		paths.SetColor(color.NRGBA{255, 255, 255, 60})
+		paths.SetLineWidth(3)
+		for n := range egos {
+			ego := &egos[n]
+			orbits.Trace(paths, ego.orbit, s/2, s/2, ego.Angle, ego.Angle+frames*ego.Speed)
+			paths.Stroke()
+		}
+	}
+
+	// A loop has no beginning, so the trails start out as long as
+	// they'll be, from going around the whole loop once beforehand
+	if loop && trailLength > 0 {
+		for i := 0; i < frames*blurSamples; i++ {
+			for n := range egos {
+				egos[n].travel(1.0 / blurSamples)
+				egos[n].remember(trailLength * blurSamples)
+			}
+		}
+	}
+
+	drawEgos := func(dc *gg.Context) {
+		for n := range egos {
+			ego := &egos[n]
+
+			// Draw the position
+			dc.SetColor(color.RGBA{255, 0, 0, 255})
+			dc.DrawCircle(ego.X+s/2, ego.Y+s/2, egoSize)
+			dc.Fill()
+		}
+	}
+	var shutter *exposure
+	if blurSamples > 1 {
+		shutter = newExposure(int(s), int(s))
 	}
 
 	// Rotate all the egos until we complete a circle,
 	// and then, for each rotation...
-	for i := 0; i < 360; i++ {
+	progress := report.NewProgress(frames)
+	for i := 0; i < frames; i++ {
 		// Start a drawing context
 		dc := gg.NewContext(int(s), int(s))
 
@@ -54,24 +163,62 @@ func main() {
 		dc.SetColor(color.RGBA{0, 0, 0, 255})
 		dc.Clear()
 
-		// Draw all the egos
-		for n := range egos {
-			ego := &egos[n]
-			ego.travel()
+		// Draw the orbits
+		dc.DrawImage(paths.Image(), 0, 0)
 
-			// Draw the orbit
-			dc.SetColor(color.NRGBA{255, 255, 255, 60})
-			dc.DrawCircle(s/2, s/2, ego.Radius)
-			dc.SetLineWidth(3)
-			dc.Stroke()
+		// The physics moves the egos on a whole frame at once,
+		// so the samples go straight from where they were
+		var start [][2]float64
+		if world != nil {
+			for n := range egos {
+				start = append(start, [2]float64{egos[n].X, egos[n].Y})
+			}
+			for _, gone := range world.Step(i) {
+				egos = append(egos[:gone], egos[gone+1:]...)
+				start = append(start[:gone], start[gone+1:]...)
+			}
+		}
 
-			// Draw the position
-			dc.SetColor(color.RGBA{255, 0, 0, 255})
-			dc.DrawCircle(ego.X+s/2, ego.Y+s/2, 12)
-			dc.Fill()
+		// Move all the egos, a sample at a time
+		for sample := 1; sample <= blurSamples; sample++ {
+			for n := range egos {
+				if world != nil {
+					t := float64(sample) / blurSamples
+					x, y := world.At(n)
+					egos[n].X = start[n][0] + (x-start[n][0])*t
+					egos[n].Y = start[n][1] + (y-start[n][1])*t
+				} else {
+					egos[n].travel(1.0 / blurSamples)
+				}
+				if trailLength > 0 {
+					egos[n].remember(trailLength * blurSamples)
+				}
+			}
+
+			if shutter != nil {
+				shutter.expose(drawEgos)
+			}
+		}
+
+		// Draw the trails, under the egos
+		if trailLength > 0 {
+			dc.SetLineWidth(egoSize)
+			for n := range egos {
+				drawTrail(dc, egos[n].trail, trailLength*blurSamples, fade, color.NRGBA{255, 0, 0, 160}, s/2, s/2)
+			}
+		}
+
+		// Draw all the egos
+		if shutter != nil {
+			dc.DrawImage(shutter.develop(), 0, 0)
+		} else {
+			drawEgos(dc)
 		}
 
 		// Save the output
-		dc.SavePNG(fmt.Sprintf("i-%d.png", i))
+		if err := dc.SavePNG(fmt.Sprintf("i-%d.png", i)); err != nil {
+			log.Fatal(err)
+		}
+		progress.Frame()
 	}
 }
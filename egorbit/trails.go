@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// fades turn how old a point along a trail is, from 0 for where the ego
+// is now to 1 for the end of the trail, into how much of it still shows
+var fades = map[string]func(age float64) float64{
+	"linear": func(age float64) float64 { return 1 - age },
+	// Down to a hundredth at the end
+	"exponential": func(age float64) float64 { return math.Pow(0.01, age) },
+}
+
+// remember adds where the ego is now to its trail,
+// and forgets what's further back than the length
+func (v *ego) remember(length int) {
+	v.trail = append(v.trail, [2]float64{v.X, v.Y})
+	if len(v.trail) > length+1 {
+		v.trail = v.trail[len(v.trail)-length-1:]
+	}
+}
+
+// drawTrail draws the trail behind an ego, fading out as it goes back,
+// for trails as long as length, in the line width already set
+func drawTrail(dc *gg.Context, trail [][2]float64, length int, fade func(float64) float64, c color.NRGBA, cx, cy float64) {
+	// Every piece on its own, so it can fade, and without
+	// round ends, which would show where they overlap
+	dc.Push()
+	defer dc.Pop()
+	dc.SetLineCapButt()
+	for i := 1; i < len(trail); i++ {
+		age := float64(len(trail)-i) / float64(length)
+		a, b := trail[i-1], trail[i]
+		dc.SetColor(color.NRGBA{c.R, c.G, c.B, uint8(float64(c.A) * math.Max(0, fade(age)))})
+		dc.DrawLine(a[0]+cx, a[1]+cy, b[0]+cx, b[1]+cy)
+		dc.Stroke()
+	}
+}
+
+// exposure adds up the samples of a frame, like a camera
+// with its shutter open for the whole of it, for motion blur
+type exposure struct {
+	sum     []uint32
+	samples uint32
+	rect    image.Rectangle
+	scratch *gg.Context // Every sample is drawn on the same canvas
+}
+
+func newExposure(width, height int) *exposure {
+	return &exposure{
+		sum:     make([]uint32, width*height*4),
+		rect:    image.Rect(0, 0, width, height),
+		scratch: gg.NewContext(width, height),
+	}
+}
+
+// expose draws another sample, over nothing left from the one before,
+// and adds it to the others
+func (e *exposure) expose(draw func(dc *gg.Context)) {
+	e.scratch.SetColor(color.Transparent)
+	e.scratch.Clear()
+	draw(e.scratch)
+	e.add(e.scratch.Image().(*image.RGBA))
+}
+
+func (e *exposure) add(img *image.RGBA) {
+	for i, v := range img.Pix {
+		e.sum[i] += uint32(v)
+	}
+	e.samples++
+}
+
+// develop returns the average of the samples so far,
+// and starts over for the next frame
+func (e *exposure) develop() *image.RGBA {
+	img := image.NewRGBA(e.rect)
+	for i, v := range e.sum {
+		img.Pix[i] = uint8((v + e.samples/2) / e.samples)
+		e.sum[i] = 0
+	}
+	e.samples = 0
+	return img
+}
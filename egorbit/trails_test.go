@@ -0,0 +1,73 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestFades(t *testing.T) {
+	for _, c := range []struct {
+		fade      string
+		age, want float64
+	}{
+		{"linear", 0, 1},
+		{"linear", 0.25, 0.75},
+		{"linear", 0.5, 0.5},
+		{"linear", 1, 0},
+		{"exponential", 0, 1},
+		{"exponential", 0.5, 0.1},
+		{"exponential", 1, 0.01},
+	} {
+		if got := fades[c.fade](c.age); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s fade at %v = %v, want %v", c.fade, c.age, got, c.want)
+		}
+	}
+
+	// Always fainter further back
+	for name, fade := range fades {
+		for age := 0.0; age < 1; age += 0.01 {
+			if fade(age+0.01) >= fade(age) {
+				t.Errorf("%s fade doesn't get fainter from %v to %v", name, age, age+0.01)
+			}
+		}
+	}
+}
+
+func TestDrawTrailLeavesTheContextAsItWas(t *testing.T) {
+	dc := gg.NewContext(20, 20)
+	dc.SetLineWidth(4)
+	dc.SetColor(color.White)
+	drawTrail(dc, [][2]float64{{0, 0}, {5, 5}, {10, 5}}, 2, fades["linear"], color.NRGBA{255, 0, 0, 255}, 5, 5)
+
+	// Still white, round ends and all
+	dc.DrawLine(2, 18, 18, 18)
+	dc.Stroke()
+	if r, g, b, _ := dc.Image().At(10, 18).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("line after the trail is %v, %v, %v, want white", r, g, b)
+	}
+	if _, _, _, a := dc.Image().At(1, 18).RGBA(); a == 0 {
+		t.Error("line after the trail has no round end")
+	}
+}
+
+func TestExposureStartsEverySampleOver(t *testing.T) {
+	e := newExposure(4, 4)
+	e.expose(func(dc *gg.Context) {
+		dc.SetColor(color.White)
+		dc.DrawRectangle(0, 0, 2, 4)
+		dc.Fill()
+	})
+	e.expose(func(dc *gg.Context) {})
+
+	// Half the time on the left, and never on the right
+	img := e.develop()
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{128, 128, 128, 128}) {
+		t.Errorf("left side %v, want half white", got)
+	}
+	if got := img.RGBAAt(3, 0); got != (color.RGBA{}) {
+		t.Errorf("right side %v, want nothing", got)
+	}
+}
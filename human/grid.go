@@ -0,0 +1,424 @@
+package main
+
+import (
+	"math"
+)
+
+// Grid describes the layout of a maze: how many cells there are,
+// how they connect to each other and how they're drawn
+type Grid interface {
+	// columns is the number of cell columns (or rings) in the grid
+	columns() int
+	// rows is the number of cells in column x
+	rows(x int) int
+	// directions lists every way out of the cell at x, y
+	directions(x, y int) []Direction
+	// neighbour returns the cell reached when leaving x, y towards dir,
+	// and the direction through which that cell is entered
+	neighbour(x, y int, dir Direction) (int, int, Direction, bool)
+	// weaves is true when passages can cross over each other
+	weaves() bool
+	// start is the cell where the maze generation begins
+	start() (int, int)
+	// shape is where the cell at x, y lies on a canvas of the given size
+	shape(x, y int, w, h float64) cellShape
+}
+
+// newGrid returns a grid by its topology name
+func newGrid(topology string, width, height int) Grid {
+	switch topology {
+	case "hex":
+		return &hexGrid{width, height}
+	case "triangle":
+		return &triangleGrid{width, height}
+	case "polar":
+		return newPolarGrid(height)
+	default:
+		return &squareGrid{width, height}
+	}
+}
+
+// squareGrid is the classic maze layout, with four exits per cell
+type squareGrid struct {
+	width, height int
+}
+
+func (g *squareGrid) columns() int {
+	return g.width
+}
+
+func (g *squareGrid) rows(x int) int {
+	return g.height
+}
+
+func (g *squareGrid) directions(x, y int) []Direction {
+	return []Direction{dirE, dirW, dirS, dirN}
+}
+
+func (g *squareGrid) neighbour(x, y int, dir Direction) (int, int, Direction, bool) {
+	dX, dY := dir.coordinatesDelta()
+	newX, newY := x+dX, y+dY
+	if newX < 0 || newX >= g.width || newY < 0 || newY >= g.height {
+		return 0, 0, dirNone, false
+	}
+
+	return newX, newY, dir.opposite(), true
+}
+
+func (g *squareGrid) weaves() bool {
+	return true
+}
+
+func (g *squareGrid) start() (int, int) {
+	return g.width / 2, g.height / 2
+}
+
+func (g *squareGrid) shape(x, y int, w, h float64) cellShape {
+	size := w / float64(g.width)
+	left, top := float64(x)*size, float64(y)*size
+
+	return cellShape{
+		x: left + size/2, y: top + size/2,
+		size:  size,
+		sides: 4,
+		exits: map[Direction]exitShape{
+			dirN: {x: left + size/2, y: top},
+			dirS: {x: left + size/2, y: top + size},
+			dirW: {x: left, y: top + size/2},
+			dirE: {x: left + size, y: top + size/2},
+		},
+	}
+}
+
+// hexGrid lays out flat-topped hexagons in columns,
+// with every odd column shifted down by half a cell
+type hexGrid struct {
+	width, height int
+}
+
+func (g *hexGrid) columns() int {
+	return g.width
+}
+
+func (g *hexGrid) rows(x int) int {
+	return g.height
+}
+
+func (g *hexGrid) directions(x, y int) []Direction {
+	return []Direction{dirN, dirNE, dirSE, dirS, dirSW, dirNW}
+}
+
+func (g *hexGrid) neighbour(x, y int, dir Direction) (int, int, Direction, bool) {
+	newX, newY := x, y
+	switch dir {
+	case dirN:
+		newY--
+	case dirS:
+		newY++
+	case dirNE, dirNW:
+		newY += x%2 - 1
+	case dirSE, dirSW:
+		newY += x % 2
+	}
+	switch dir {
+	case dirNE, dirSE:
+		newX++
+	case dirNW, dirSW:
+		newX--
+	}
+
+	if newX < 0 || newX >= g.width || newY < 0 || newY >= g.height {
+		return 0, 0, dirNone, false
+	}
+
+	return newX, newY, dir.opposite(), true
+}
+
+func (g *hexGrid) weaves() bool {
+	return true
+}
+
+func (g *hexGrid) start() (int, int) {
+	return g.width / 2, g.height / 2
+}
+
+func (g *hexGrid) shape(x, y int, w, h float64) cellShape {
+	// Fit the hexagons' outer radius to the canvas
+	radius := math.Min(w/(1.5*float64(g.width)+0.5), h/(math.Sqrt(3)*(float64(g.height)+0.5)))
+	apothem := radius * math.Sqrt(3) / 2
+
+	// Center the grid on the canvas
+	offsetX := (w - radius*(1.5*float64(g.width)+0.5)) / 2
+	offsetY := (h - apothem*2*(float64(g.height)+0.5)) / 2
+
+	s := cellShape{
+		x:     offsetX + radius + float64(x)*radius*1.5,
+		y:     offsetY + apothem*(float64(y)*2+1+float64(x%2)),
+		size:  radius,
+		sides: 6,
+		exits: map[Direction]exitShape{},
+	}
+
+	// Exits are in the middle of each side
+	for _, dir := range g.directions(x, y) {
+		sin, cos := math.Sincos(dir.radians())
+		s.exits[dir] = exitShape{x: s.x + apothem*cos, y: s.y + apothem*sin}
+	}
+
+	return s
+}
+
+// triangleGrid alternates upwards and downwards pointing triangles,
+// each of them sharing its edges with three neighbours
+type triangleGrid struct {
+	width, height int
+}
+
+func (g *triangleGrid) upwards(x, y int) bool {
+	return (x+y)%2 == 0
+}
+
+func (g *triangleGrid) columns() int {
+	return g.width
+}
+
+func (g *triangleGrid) rows(x int) int {
+	return g.height
+}
+
+func (g *triangleGrid) directions(x, y int) []Direction {
+	if g.upwards(x, y) {
+		return []Direction{dirE, dirW, dirS}
+	}
+	return []Direction{dirE, dirW, dirN}
+}
+
+func (g *triangleGrid) neighbour(x, y int, dir Direction) (int, int, Direction, bool) {
+	// Upwards triangles have no northern edge,
+	// and downwards ones no southern edge
+	if (dir == dirN && g.upwards(x, y)) || (dir == dirS && !g.upwards(x, y)) {
+		return 0, 0, dirNone, false
+	}
+
+	dX, dY := dir.coordinatesDelta()
+	newX, newY := x+dX, y+dY
+	if newX < 0 || newX >= g.width || newY < 0 || newY >= g.height {
+		return 0, 0, dirNone, false
+	}
+
+	return newX, newY, dir.opposite(), true
+}
+
+func (g *triangleGrid) weaves() bool {
+	// There's no straight path through a triangle
+	return false
+}
+
+func (g *triangleGrid) start() (int, int) {
+	return g.width / 2, g.height / 2
+}
+
+func (g *triangleGrid) shape(x, y int, w, h float64) cellShape {
+	// Fit the triangles' side to the canvas
+	side := math.Min(w*2/float64(g.width+1), h/(float64(g.height)*math.Sqrt(3)/2))
+	height := side * math.Sqrt(3) / 2
+
+	// Center the grid on the canvas
+	left := (w-side*float64(g.width+1)/2)/2 + float64(x)*side/2
+	top := (h-height*float64(g.height))/2 + float64(y)*height
+
+	// Corridors any wider than two thirds of the side would
+	// poke out through the other sides. The core is a hexagon,
+	// so it can fill the corners where corridors meet.
+	s := cellShape{
+		x:     left + side/2,
+		y:     top + height*2/3,
+		size:  side * 2 / 3,
+		sides: 6,
+		exits: map[Direction]exitShape{
+			dirW: {x: left + side/4, y: top + height/2},
+			dirE: {x: left + side*3/4, y: top + height/2},
+			dirS: {x: left + side/2, y: top + height},
+		},
+	}
+
+	if !g.upwards(x, y) {
+		s.y = top + height/3
+		delete(s.exits, dirS)
+		s.exits[dirN] = exitShape{x: left + side/2, y: top}
+	}
+
+	return s
+}
+
+// polarGrid arranges cells in concentric rings around an empty center.
+// Rings subdivide outwards to keep cells roughly square, so a cell
+// leads outwards either to a single cell (dirS) or to two (dirSW, dirSE).
+// Inwards is dirN, and clockwise and counter-clockwise are dirE and dirW.
+type polarGrid struct {
+	counts []int
+}
+
+func newPolarGrid(rings int) *polarGrid {
+	g := &polarGrid{counts: make([]int, rings)}
+
+	// The empty center is one ring high, so ring x's
+	// middle radius is x+1.5 ring heights
+	for x := 0; x < rings; x++ {
+		circumference := 2 * math.Pi * (float64(x) + 1.5)
+		if x == 0 {
+			g.counts[x] = int(math.Round(circumference))
+			continue
+		}
+
+		ratio := int(math.Round(circumference / float64(g.counts[x-1])))
+		if ratio < 1 {
+			ratio = 1
+		}
+		if ratio > 2 {
+			ratio = 2
+		}
+		g.counts[x] = g.counts[x-1] * ratio
+	}
+
+	return g
+}
+
+// ratio is how many cells of ring x border each cell of the ring inside it
+func (g *polarGrid) ratio(x int) int {
+	return g.counts[x] / g.counts[x-1]
+}
+
+func (g *polarGrid) columns() int {
+	return len(g.counts)
+}
+
+func (g *polarGrid) rows(x int) int {
+	return g.counts[x]
+}
+
+func (g *polarGrid) directions(x, y int) []Direction {
+	dirs := []Direction{dirE, dirW}
+	if x > 0 {
+		dirs = append(dirs, dirN)
+	}
+	if x < len(g.counts)-1 {
+		if g.ratio(x+1) == 1 {
+			dirs = append(dirs, dirS)
+		} else {
+			dirs = append(dirs, dirSW, dirSE)
+		}
+	}
+	return dirs
+}
+
+func (g *polarGrid) neighbour(x, y int, dir Direction) (int, int, Direction, bool) {
+	count := g.counts[x]
+
+	switch dir {
+	case dirE:
+		return x, (y + 1) % count, dirW, true
+	case dirW:
+		return x, (y + count - 1) % count, dirE, true
+	case dirN:
+		if x == 0 {
+			break
+		}
+		ratio := g.ratio(x)
+		if ratio == 1 {
+			return x - 1, y, dirS, true
+		}
+		if y%2 == 0 {
+			return x - 1, y / ratio, dirSW, true
+		}
+		return x - 1, y / ratio, dirSE, true
+	case dirS, dirSW, dirSE:
+		if x == len(g.counts)-1 {
+			break
+		}
+		ratio := g.ratio(x + 1)
+		if ratio == 1 && dir == dirS {
+			return x + 1, y, dirN, true
+		}
+		if ratio == 2 && dir == dirSW {
+			return x + 1, y * 2, dirN, true
+		}
+		if ratio == 2 && dir == dirSE {
+			return x + 1, y*2 + 1, dirN, true
+		}
+	}
+
+	return 0, 0, dirNone, false
+}
+
+func (g *polarGrid) weaves() bool {
+	// Rings don't line up with each other,
+	// so there's no straight path through a cell
+	return false
+}
+
+func (g *polarGrid) start() (int, int) {
+	return len(g.counts) / 2, 0
+}
+
+func (g *polarGrid) shape(x, y int, w, h float64) cellShape {
+	centerX, centerY := w/2, h/2
+
+	// One extra ring for the empty center
+	ring := math.Min(w, h) / 2 / float64(len(g.counts)+1)
+	inner := float64(x+1) * ring
+	middle := inner + ring/2
+
+	step := 2 * math.Pi / float64(g.counts[x])
+	start := float64(y) * step
+	angle := start + step/2
+
+	point := func(radius, angle float64) (float64, float64) {
+		sin, cos := math.Sincos(angle)
+		return centerX + radius*cos, centerY + radius*sin
+	}
+	along := func(to float64) func(t tracer) {
+		return func(t tracer) {
+			t.DrawArc(centerX, centerY, middle, angle, to)
+		}
+	}
+	// Outwards passages bend into the cell beyond
+	outwards := func(to float64) exitShape {
+		ex, ey := point(inner+ring, to)
+		return exitShape{x: ex, y: ey, trace: func(t tracer) {
+			t.DrawArc(centerX, centerY, middle, angle, to)
+			t.LineTo(ex, ey)
+		}}
+	}
+
+	s := cellShape{
+		size:     ring,
+		sides:    4,
+		rotation: angle,
+		exits:    map[Direction]exitShape{},
+	}
+	s.x, s.y = point(middle, angle)
+
+	for _, dir := range g.directions(x, y) {
+		switch dir {
+		case dirE:
+			ex, ey := point(middle, start+step)
+			s.exits[dir] = exitShape{x: ex, y: ey, trace: along(start + step)}
+		case dirW:
+			ex, ey := point(middle, start)
+			s.exits[dir] = exitShape{x: ex, y: ey, trace: along(start)}
+		case dirN:
+			ex, ey := point(inner, angle)
+			s.exits[dir] = exitShape{x: ex, y: ey}
+		case dirS:
+			ex, ey := point(inner+ring, angle)
+			s.exits[dir] = exitShape{x: ex, y: ey}
+		case dirSW:
+			s.exits[dir] = outwards(start + step/4)
+		case dirSE:
+			s.exits[dir] = outwards(start + step*3/4)
+		}
+	}
+
+	return s
+}
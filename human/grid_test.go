@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+var topologies = []string{"square", "hex", "triangle", "polar"}
+
+func TestNeighboursAreSymmetric(t *testing.T) {
+	for _, topology := range topologies {
+		for _, size := range []int{1, 2, 5, 12} {
+			grid := newGrid(topology, size, size)
+			for x := 0; x < grid.columns(); x++ {
+				for y := 0; y < grid.rows(x); y++ {
+					for _, dir := range grid.directions(x, y) {
+						nx, ny, entry, ok := grid.neighbour(x, y, dir)
+						if !ok {
+							continue
+						}
+						if nx < 0 || nx >= grid.columns() || ny < 0 || ny >= grid.rows(nx) {
+							t.Fatalf("%s %d: %d,%d %s leads off the grid to %d,%d", topology, size, x, y, dir, nx, ny)
+						}
+
+						// The way in must be one of the cell's own ways out,
+						// and lead straight back
+						found := false
+						for _, d := range grid.directions(nx, ny) {
+							found = found || d == entry
+						}
+						if !found {
+							t.Errorf("%s %d: %d,%d %s enters %d,%d from %s, which it has no exit towards", topology, size, x, y, dir, nx, ny, entry)
+							continue
+						}
+						bx, by, back, ok := grid.neighbour(nx, ny, entry)
+						if !ok || bx != x || by != y || back != dir {
+							t.Errorf("%s %d: %d,%d %s leads to %d,%d, but %s from there leads to %d,%d entering %s", topology, size, x, y, dir, nx, ny, entry, bx, by, back)
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"image/color"
+	"log"
+	"log/slog"
 	"math/rand"
 	"strconv"
 
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
@@ -13,9 +18,13 @@ type Direction int
 
 const (
 	dirN    Direction = 0
+	dirNE   Direction = 60
 	dirE    Direction = 90
+	dirSE   Direction = 120
 	dirS    Direction = 180
+	dirSW   Direction = 240
 	dirW    Direction = 270
+	dirNW   Direction = 300
 	dirNone Direction = -1
 )
 
@@ -33,10 +42,18 @@ func (d Direction) String() string {
 	switch d {
 	case dirN:
 		return "N"
+	case dirNE:
+		return "NE"
+	case dirSE:
+		return "SE"
 	case dirS:
 		return "S"
+	case dirSW:
+		return "SW"
 	case dirW:
 		return "W"
+	case dirNW:
+		return "NW"
 	default:
 		return "E"
 	}
@@ -46,6 +63,12 @@ func (d Direction) opposite() Direction {
 	return (d + 180) % 360
 }
 
+// radians is the direction as an angle on the canvas,
+// where 0 points east and angles grow clockwise
+func (d Direction) radians() float64 {
+	return gg.Radians(float64(d - 90))
+}
+
 func (d Direction) coordinatesDelta() (int, int) {
 	switch d {
 	case dirN:
@@ -59,7 +82,9 @@ func (d Direction) coordinatesDelta() (int, int) {
 	}
 }
 
-// Position is whether the cell is under or above
+// Position is whether the cell is under or above.
+// Weaved cells use it to tell if their own passages run
+// above (posAbove) or below (posBelow) the crossing one.
 type Position uint
 
 const (
@@ -71,107 +96,26 @@ var cellsSoFar = 0
 
 // Cell represents a maze cell
 type Cell struct {
-	dirs    map[Direction]bool
-	pos     Position
-	visited bool
-	weaved  bool
-	num     int
+	dirs     map[Direction]bool
+	pos      Position
+	visited  bool
+	weaved   bool
+	crossing Direction // Direction of the passage underneath a weaved cell
+	num      int
 }
 
-func (c Cell) drawOn(dc *gg.Context, x, y, size float64) {
-	offset := size / 5.0
-	walls := map[Direction]bool{dirN: true, dirS: true, dirW: true, dirE: true}
-
-	// Exit locations and dimensions
-	exits := map[Direction][2][2]float64{
-		dirN: {{x + offset, y}, {size - offset*2, offset}},
-		dirS: {{x + offset, y + size - offset}, {size - offset*2, offset}},
-		dirW: {{x, y + offset}, {offset, size - offset*2}},
-		dirE: {{x + size - offset, y + offset}, {offset, size - offset*2}},
+// levels splits the passages of a cell into the ones on top,
+// and the ones running underneath them
+func (c Cell) levels() (map[Direction]bool, map[Direction]bool) {
+	if !c.weaved {
+		return c.dirs, map[Direction]bool{}
 	}
 
-	// Cell core
-	core := [2][2]float64{{x + offset, y + offset}, {size - offset*2, size - offset*2}}
-
-	cellColor := color.RGBA{249, 205, 173, 255}
-	patt := gg.NewSolidPattern(cellColor)
-	dc.SetFillStyle(patt)
-
-	// Draw all our exits
-	for dir := range c.dirs {
-		if _, ok := exits[dir]; ok {
-			dc.DrawRectangle(exits[dir][0][0], exits[dir][0][1], exits[dir][1][0], exits[dir][1][1])
-			dc.Fill()
-		}
-
-		// And also remove the wall
-		delete(walls, dir)
+	crossing := map[Direction]bool{c.crossing: true, c.crossing.opposite(): true}
+	if c.pos == posBelow {
+		return crossing, c.dirs
 	}
-
-	// Generate a horizontal or vertical gradient
-	// based on coordinates
-	gradientForCoords := func(coords [2][2]float64, dir Direction) gg.Gradient {
-		x0, y0, w, h := coords[0][0], coords[0][1], coords[1][0], coords[1][1]
-		x1, y1 := x0+w, y0+h
-
-		// Gradients facing north or west need to be reversed
-		if dir == dirN {
-			y0, y1 = y1, y0
-		}
-		if dir == dirW {
-			x0, x1 = x1, x0
-		}
-
-		// Horizontal gradient
-		grad := gg.NewLinearGradient(x0, y0, x1, y0)
-		// Vertical gradient
-		if dir == dirN || dir == dirS || (h > w && dir == dirNone) {
-			grad = gg.NewLinearGradient(x0, y0, x0, y1)
-		}
-		return grad
-	}
-
-	// Draw the core rectangle.
-	dc.DrawRectangle(core[0][0], core[0][1], core[1][0], core[1][0])
-
-	// Solid fill by default.
-	dc.SetFillStyle(gg.NewSolidPattern(cellColor))
-
-	// Weaved cells get special effects,
-	// and need to draw the passages underneath.
-	if c.weaved {
-		grad := gradientForCoords(core, dirNone)
-		if !dirN.isPerpendicular(c.dirs) {
-			grad = gradientForCoords(core, dirN)
-		}
-		grad.AddColorStop(0, cellColor)
-		grad.AddColorStop(0.4, color.RGBA{247, 217, 195, 255})
-		grad.AddColorStop(0.6, color.RGBA{212, 164, 131, 255})
-		grad.AddColorStop(1, cellColor)
-		dc.SetFillStyle(grad)
-		dc.Fill()
-		dc.SetColor(color.Black)
-
-		// Our walls are "exits" for the cell underneath,
-		// so we can draw them and style them differently.
-		for wall := range walls {
-			if _, ok := exits[wall]; ok {
-				grad := gradientForCoords(exits[wall], wall.opposite())
-				grad.AddColorStop(0, cellColor)
-				grad.AddColorStop(1, color.RGBA{184, 144, 116, 255})
-				dc.SetFillStyle(grad)
-				dc.DrawRectangle(exits[wall][0][0], exits[wall][0][1], exits[wall][1][0], exits[wall][1][1])
-				dc.Fill()
-			}
-		}
-
-	} else {
-		dc.Fill()
-		dc.SetColor(color.Black)
-	}
-
-	// dc.SetColor(color.Black)
-	// dc.DrawString(c.String(), x+size/2-offset, y+size/2-offset)
+	return c.dirs, crossing
 }
 
 func (c Cell) String() string {
@@ -182,26 +126,35 @@ func (c Cell) String() string {
 	return text
 }
 
+// Weaving controls how often passages cross over each other
+type Weaving struct {
+	Chance    float64 // Chance to weave whenever a passage can be crossed
+	Crossings int     // Most passages a single crossing can go past in a row
+	Bridges   float64 // Chance of going over the crossed passages instead of under
+}
+
 // Maze represents our whole maze
 type Maze struct {
-	cells         [][]*Cell
-	width, height int
+	cells   [][]*Cell
+	grid    Grid
+	weaving Weaving
 }
 
-func newMaze(width, height int) *Maze {
+func newMaze(grid Grid, weaving Weaving) *Maze {
 	m := &Maze{}
-	m.width = width
-	m.height = height
-	m.cells = make([][]*Cell, width, width)
-	for x := 0; x < width; x++ {
-		m.cells[x] = make([]*Cell, height)
-		for y := 0; y < height; y++ {
+	m.grid = grid
+	m.weaving = weaving
+	m.cells = make([][]*Cell, grid.columns())
+	for x := range m.cells {
+		m.cells[x] = make([]*Cell, grid.rows(x))
+		for y := range m.cells[x] {
 			cell := &Cell{}
-			cell.dirs = make(map[Direction]bool, 4)
+			cell.dirs = make(map[Direction]bool, len(grid.directions(x, y)))
 			m.cells[x][y] = cell
 		}
 	}
-	m.visitCell(width/2, height/2, dirNone)
+	x, y := grid.start()
+	m.visitCell(x, y, dirNone)
 	return m
 }
 
@@ -215,90 +168,217 @@ func (m *Maze) visitCell(x, y int, dir Direction) {
 	cell.num = cellsSoFar
 
 	// Get all the possible directions and randomize them
-	directions := [4]Direction{dirE, dirW, dirS, dirN}
+	directions := m.grid.directions(x, y)
 	rand.Shuffle(len(directions), func(i, j int) {
 		directions[i], directions[j] = directions[j], directions[i]
 	})
 	// Then, return the first available cell
 	for _, newDir := range directions {
-		dX, dY := newDir.coordinatesDelta()
-		newX, newY := x+dX, y+dY
-		if m.canVisit(newX, newY) {
-			nextCell := m.cells[newX][newY]
-
-			// Weave when possible
-			if nextCell.visited {
-				if newDir.isPerpendicular(nextCell.dirs) && m.canVisit(newX+dX, newY+dY) && !m.cells[newX+dX][newY+dY].visited {
-					cell.dirs[newDir] = true
-					nextCell.weaved = true
-					m.visitCell(newX+dX, newY+dY, newDir.opposite())
-					continue
+		newX, newY, entry, ok := m.grid.neighbour(x, y, newDir)
+		if !ok {
+			continue
+		}
+		nextCell := m.cells[newX][newY]
+
+		// Weave when possible
+		if nextCell.visited {
+			crossed, ok := m.canWeave(newDir, newX, newY)
+			if ok && m.roll(m.weaving.Chance) {
+				pos := posAbove
+				if m.roll(m.weaving.Bridges) {
+					pos = posBelow
 				}
-			}
 
-			// Or if it's free to go
-			if !nextCell.visited {
 				cell.dirs[newDir] = true
-				m.visitCell(newX, newY, newDir.opposite())
+				for i := 0; i < crossed; i++ {
+					nextCell.weaved = true
+					nextCell.crossing = newDir
+					nextCell.pos = pos
+					newX, newY, entry, _ = m.grid.neighbour(newX, newY, newDir)
+					nextCell = m.cells[newX][newY]
+				}
+				m.visitCell(newX, newY, entry)
+				continue
 			}
 		}
+
+		// Or if it's free to go
+		if !nextCell.visited {
+			cell.dirs[newDir] = true
+			m.visitCell(newX, newY, entry)
+		}
 	}
 }
 
-func (m *Maze) canVisit(x, y int) bool {
-	// Is it out of bounds?
-	if x < 0 || x >= len(m.cells) || y < 0 || y >= len(m.cells[0]) {
-		return false
+// canWeave checks if a passage can go from x, y towards dir,
+// crossing over (or under) the visited cells in the way until it
+// reaches one that hasn't been visited yet, and how many it crosses
+func (m *Maze) canWeave(dir Direction, x, y int) (int, bool) {
+	if !m.grid.weaves() {
+		return 0, false
 	}
 
-	return true
-}
+	for crossed := 1; crossed <= m.weaving.Crossings; crossed++ {
+		cell := m.cells[x][y]
+		// Only a straight passage going the other way
+		// can be crossed, and only once
+		if cell.weaved || !dir.isPerpendicular(cell.dirs) {
+			return 0, false
+		}
 
-func (m *Maze) canWeave(dir Direction, x, y int) bool {
-	return false
-	cell := m.cells[x][y]
-	// If both cells are horizontal or vertical,
-	// then we can't weave
-	if dir.isPerpendicular(cell.dirs) {
-		return false
+		// Where would we end up if we tried to move past x and y?
+		newX, newY, _, ok := m.grid.neighbour(x, y, dir)
+		if !ok {
+			return 0, false
+		}
+		if !m.cells[newX][newY].visited {
+			return crossed, true
+		}
+		x, y = newX, newY
 	}
 
-	// Where would we end up if we tried to move
-	// past x and y?
-	newX, newY := dir.coordinatesDelta()
-	newX = newX*2 + x // Twice, to get out on the other side
-	newY = newY*2 + y
+	return 0, false
+}
 
-	return m.canVisit(newX, newY)
+// roll returns true with the given chance, without
+// touching the random generator when it's a sure thing
+func (m *Maze) roll(chance float64) bool {
+	if chance >= 1 {
+		return true
+	}
+	if chance <= 0 {
+		return false
+	}
+	return rand.Float64() < chance
 }
 
-func (m *Maze) drawOn(dc *gg.Context) {
-	scale := float64(dc.Width()) / float64(m.width)
+// validate checks that every crossing in the maze is legal:
+// the passage underneath must go straight across the cell without
+// touching the cell's own passages, and come out on both sides,
+// either into a cell or under the next crossing of the same run
+func (m *Maze) validate() error {
+	for x := range m.cells {
+		for y, cell := range m.cells[x] {
+			if !cell.weaved {
+				continue
+			}
+			if !m.grid.weaves() {
+				return fmt.Errorf("cell %d,%d is weaved on a grid without weaving", x, y)
+			}
+			if !cell.crossing.isPerpendicular(cell.dirs) {
+				return fmt.Errorf("cell %d,%d is crossed along its own passage %s", x, y, cell.crossing)
+			}
+
+			for _, dir := range []Direction{cell.crossing, cell.crossing.opposite()} {
+				newX, newY, entry, ok := m.grid.neighbour(x, y, dir)
+				if !ok {
+					return fmt.Errorf("crossing at cell %d,%d leads %s out of the maze", x, y, dir)
+				}
 
-	for x := 0; x < m.width; x++ {
-		for y := 0; y < m.height; y++ {
-			m.cells[x][y].drawOn(dc, float64(x)*scale, float64(y)*scale, scale)
+				next := m.cells[newX][newY]
+				sameRun := next.weaved && (next.crossing == dir || next.crossing == dir.opposite())
+				if !next.dirs[entry] && !sameRun {
+					return fmt.Errorf("crossing at cell %d,%d leads %s into a wall", x, y, dir)
+				}
+			}
 		}
 	}
+
+	return nil
 }
 
 func main() {
 	const (
 		s        = 5000
 		mazeSize = 7
+		topology = "square" // One of square, hex, triangle or polar
+		seed     = 1073 / (1337 + 42)
+
+		// When above zero, try this many seeds and keep the
+		// maze with the difficulty closest to the target
+		attempts         = 0
+		targetDifficulty = 50.0
+
+		logLevel = slog.LevelInfo // slog.LevelWarn to leave out the metrics
 	)
+	report.Log(logLevel)
+
+	weaving := Weaving{
+		Chance:    1,
+		Crossings: 1,
+		Bridges:   0,
+	}
+	style := Style{
+		Palette:  palettes["heart"],
+		Corridor: 0.6,
+		Rounded:  false,
+		Walls:    0,
+		Shadows:  false,
+	}
+	puzzles := flag.Int("puzzles", 0, "print this many mazes as puzzle sheets instead of drawing one")
+	perPage := flag.Int("per-page", 4, "mazes on every puzzle sheet")
+	pdfPath := flag.String("pdf", "puzzles.pdf", "where to save the puzzle sheets")
+	flag.Parse()
+
+	grid := newGrid(topology, mazeSize, mazeSize)
+
+	// PUZZLE SHEETS, WITH THEIR ANSWERS AT THE END
+	if *puzzles > 0 {
+		// Before all the work of making the mazes
+		if *perPage < 1 {
+			log.Fatalf("need at least one maze on every page, not -per-page %d", *perPage)
+		}
+
+		mazes := []*Maze{}
+		for i := 0; i < *puzzles; i++ {
+			// Every maze gets its own seeds
+			var maze *Maze
+			if attempts > 0 {
+				maze, _ = searchMaze(grid, weaving, seed+int64(i*attempts), attempts, targetDifficulty)
+			} else {
+				rand.Seed(seed + int64(i))
+				maze = newMaze(grid, weaving)
+			}
+			if err := maze.validate(); err != nil {
+				log.Fatal(err)
+			}
+			mazes = append(mazes, maze)
+		}
+
+		paper := Style{
+			Palette:  palettes["print"],
+			Corridor: 0.6,
+			Walls:    0.05,
+		}
+		if err := printPuzzles(*pdfPath, mazes, *perPage, paper); err != nil {
+			log.Fatal(err)
+		}
+		slog.Info("puzzles saved", "count", len(mazes), "path", *pdfPath)
+		return
+	}
+
 	dc := gg.NewContext(int(s), int(s))
 
-	rand.Seed(1073 / (1337 + 42))
+	rand.Seed(seed)
 
 	// Set a background color
-	dc.SetColor(color.RGBA{131, 175, 155, 255})
+	dc.SetColor(style.Palette.Background)
 	dc.Clear()
 
 	// MAZE WITH A HEART IN THE CENTER
-	maze := newMaze(mazeSize, mazeSize)
-	dc.SetColor(color.Black)
-	maze.drawOn(dc)
+	var maze *Maze
+	if attempts > 0 {
+		var best int64
+		maze, best = searchMaze(grid, weaving, seed, attempts, targetDifficulty)
+		slog.Info("closest to the target difficulty", "seed", best)
+	} else {
+		maze = newMaze(grid, weaving)
+	}
+	slog.Info("maze", "metrics", maze.analyze())
+	if err := maze.validate(); err != nil {
+		log.Fatal(err)
+	}
+	maze.drawOn(dc, style)
 
 	// Draw a heart
 	dc.SetColor(color.RGBA{254, 67, 101, 255})
@@ -306,5 +386,8 @@ func main() {
 	dc.DrawString("♥", s*0.478, s*0.52)
 
 	// Save the output
-	dc.SavePNG("output.png")
+	if err := dc.SavePNG("output.png"); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("saved", "path", "output.png")
 }
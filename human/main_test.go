@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMazesAreValidAndConnected(t *testing.T) {
+	for _, topology := range topologies {
+		weaves, runs := 0, 0
+		for _, size := range []int{1, 2, 5, 12} {
+			for _, crossings := range []int{0, 1, 3} {
+				for seed := int64(1); seed <= 5; seed++ {
+					rand.Seed(seed)
+					grid := newGrid(topology, size, size)
+					m := newMaze(grid, Weaving{Chance: 0.7, Crossings: crossings, Bridges: 0.5})
+
+					if err := m.validate(); err != nil {
+						t.Fatalf("%s %d, %d crossings, seed %d: %v", topology, size, crossings, seed, err)
+					}
+
+					// Every cell can be reached, and every passage
+					// can be walked back the way it came
+					cells := 0
+					for x := range m.cells {
+						for y, cell := range m.cells[x] {
+							cells++
+							if cell.weaved {
+								weaves++
+								// Crossed along with the next cell over, in a run
+								if nx, ny, _, ok := grid.neighbour(x, y, cell.crossing); ok && m.cells[nx][ny].weaved && m.cells[nx][ny].crossing == cell.crossing {
+									runs++
+								}
+							}
+							if cell.weaved && crossings == 0 {
+								t.Errorf("%s %d, seed %d: cell %d,%d is weaved with no crossings allowed", topology, size, seed, x, y)
+							}
+							for _, next := range m.links(point{x, y}) {
+								back := false
+								for _, p := range m.links(next) {
+									back = back || p == (point{x, y})
+								}
+								if !back {
+									t.Errorf("%s %d, %d crossings, seed %d: %d,%d leads to %v, with no way back", topology, size, crossings, seed, x, y, next)
+								}
+							}
+						}
+					}
+					start, _ := m.ends()
+					if dist, _ := m.distances(start); len(dist) != cells {
+						t.Errorf("%s %d, %d crossings, seed %d: %d of %d cells reachable", topology, size, crossings, seed, len(dist), cells)
+					}
+				}
+			}
+		}
+
+		if newGrid(topology, 1, 1).weaves() && (weaves == 0 || runs == 0) {
+			t.Errorf("%s: %d weaved cells and %d runs, the mazes should weave", topology, weaves, runs)
+		}
+	}
+}
+
+func TestValidateCatchesCrossingsIntoWalls(t *testing.T) {
+	// A passage running east under the middle of three cells,
+	// with nothing to come out into on the east side
+	m := &Maze{grid: newGrid("square", 3, 1), cells: [][]*Cell{
+		{{dirs: map[Direction]bool{dirE: true}}},
+		{{dirs: map[Direction]bool{}, weaved: true, crossing: dirE}},
+		{{dirs: map[Direction]bool{}}},
+	}}
+	if err := m.validate(); err == nil {
+		t.Error("a crossing into a wall passed validation")
+	}
+
+	m.cells[2][0].dirs[dirW] = true
+	if err := m.validate(); err != nil {
+		t.Errorf("a crossing coming out on both sides failed validation: %v", err)
+	}
+
+	// Crossing along the cell's own passage
+	m.cells[1][0].dirs[dirE] = true
+	if err := m.validate(); err == nil {
+		t.Error("a crossing along the cell's own passage passed validation")
+	}
+}
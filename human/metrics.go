@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// point is the location of a cell in the maze
+type point struct {
+	x, y int
+}
+
+// Metrics describe how a maze is structured, and how hard it is to solve
+type Metrics struct {
+	Cells           int
+	DeadEnds        int     // Cells with a single passage
+	BranchingFactor float64 // Average number of ways forward at every cell that isn't a dead end
+	RiverFactor     float64 // Average length of the dead-end branches, long ones meander like rivers
+	LongestPath     int     // Cells along the longest path in the maze
+	SolutionLength  int     // Cells along the path from start to finish
+	Decisions       int     // Wrong turns available along the solution
+	Weaves          int     // Cells crossed by another passage
+	Difficulty      float64 // Composite score, from 0 (trivial) to 100
+}
+
+func (mt Metrics) String() string {
+	return fmt.Sprintf(
+		"cells: %d, dead ends: %d, branching: %.2f, river: %.2f, longest path: %d, solution: %d, decisions: %d, weaves: %d, difficulty: %.1f",
+		mt.Cells, mt.DeadEnds, mt.BranchingFactor, mt.RiverFactor, mt.LongestPath, mt.SolutionLength, mt.Decisions, mt.Weaves, mt.Difficulty,
+	)
+}
+
+// ends returns where the maze starts and where it finishes:
+// the first cell of the first column and the last cell of the last one.
+// On polar grids, that's from the center to the outside.
+func (m *Maze) ends() (point, point) {
+	last := len(m.cells) - 1
+	return point{0, 0}, point{last, len(m.cells[last]) - 1}
+}
+
+// follow returns the cell reached leaving p towards dir,
+// going under or over any crossings along the way
+func (m *Maze) follow(p point, dir Direction) (point, bool) {
+	x, y, entry, ok := m.grid.neighbour(p.x, p.y, dir)
+	for ok {
+		next := m.cells[x][y]
+		if next.dirs[entry] || !next.weaved || (next.crossing != dir && next.crossing != dir.opposite()) {
+			return point{x, y}, true
+		}
+		x, y, entry, ok = m.grid.neighbour(x, y, dir)
+	}
+	return point{}, false
+}
+
+// links returns the cells the one at p leads to
+func (m *Maze) links(p point) []point {
+	links := []point{}
+	for dir := range m.cells[p.x][p.y].dirs {
+		if next, ok := m.follow(p, dir); ok {
+			links = append(links, next)
+		}
+	}
+	return links
+}
+
+// distances walks the maze from a cell, returning how far every
+// other cell is and which cell comes before it on the way there
+func (m *Maze) distances(from point) (map[point]int, map[point]point) {
+	dist := map[point]int{from: 0}
+	prev := map[point]point{}
+	queue := []point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, next := range m.links(p) {
+			if _, ok := dist[next]; ok {
+				continue
+			}
+			dist[next] = dist[p] + 1
+			prev[next] = p
+			queue = append(queue, next)
+		}
+	}
+	return dist, prev
+}
+
+// farthest returns the cell furthest away from the given distances
+func farthest(dist map[point]int) (point, int) {
+	far, most := point{}, -1
+	for p, d := range dist {
+		if d > most || (d == most && (p.x < far.x || (p.x == far.x && p.y < far.y))) {
+			far, most = p, d
+		}
+	}
+	return far, most
+}
+
+// solution returns the cells from the start of the maze to its finish
+func (m *Maze) solution() []point {
+	start, finish := m.ends()
+	dist, prev := m.distances(start)
+	if _, ok := dist[finish]; !ok {
+		return nil
+	}
+
+	path := make([]point, dist[finish]+1)
+	for p, i := finish, len(path)-1; i >= 0; i-- {
+		path[i] = p
+		p = prev[p]
+	}
+	return path
+}
+
+// analyze measures the structure and the difficulty of the maze
+func (m *Maze) analyze() Metrics {
+	mt := Metrics{}
+	degree := map[point]int{}
+	ways := 0
+
+	for x := range m.cells {
+		for y, cell := range m.cells[x] {
+			p := point{x, y}
+			degree[p] = len(m.links(p))
+			mt.Cells++
+			if cell.weaved {
+				mt.Weaves++
+			}
+			if degree[p] == 1 {
+				mt.DeadEnds++
+			} else {
+				ways += degree[p] - 1
+			}
+		}
+	}
+	if mt.Cells > mt.DeadEnds {
+		mt.BranchingFactor = float64(ways) / float64(mt.Cells-mt.DeadEnds)
+	}
+
+	// Follow every dead end back to the junction it branches off from
+	branches := 0
+	for p, d := range degree {
+		if d != 1 {
+			continue
+		}
+		prev, cur := p, m.links(p)[0]
+		length := 1
+		for degree[cur] == 2 {
+			for _, next := range m.links(cur) {
+				if next != prev {
+					prev, cur = cur, next
+					break
+				}
+			}
+			length++
+		}
+		branches += length
+	}
+	if mt.DeadEnds > 0 {
+		mt.RiverFactor = float64(branches) / float64(mt.DeadEnds)
+	}
+
+	// The longest path runs between the two cells furthest apart,
+	// and the first of them is the furthest from any cell
+	start, _ := m.ends()
+	dist, _ := m.distances(start)
+	far, _ := farthest(dist)
+	dist, _ = m.distances(far)
+	_, longest := farthest(dist)
+	mt.LongestPath = longest + 1
+
+	solution := m.solution()
+	mt.SolutionLength = len(solution)
+	for _, p := range solution {
+		if degree[p] > 2 {
+			mt.Decisions += degree[p] - 2
+		}
+	}
+
+	mt.Difficulty = difficulty(mt)
+	return mt
+}
+
+// difficulty combines how much of the maze the solution covers,
+// how many wrong turns it offers and how often passages cross
+// into a single score between 0 and 100
+func difficulty(mt Metrics) float64 {
+	if mt.Cells == 0 || mt.SolutionLength == 0 {
+		return 0
+	}
+
+	coverage := float64(mt.SolutionLength) / float64(mt.Cells)
+	// A wrong turn every other cell is about as confusing as it gets
+	decisions := math.Min(1, float64(mt.Decisions)*2/float64(mt.SolutionLength))
+	// So is a crossing every fifth cell
+	weaves := math.Min(1, float64(mt.Weaves)*5/float64(mt.Cells))
+
+	return 100 * (coverage*0.45 + decisions*0.4 + weaves*0.15)
+}
+
+// searchMaze generates a maze for each of the given number of seeds,
+// and keeps the one with the difficulty closest to the target
+func searchMaze(grid Grid, weaving Weaving, seed int64, attempts int, target float64) (*Maze, int64) {
+	var best *Maze
+	bestSeed, bestDistance := seed, math.Inf(1)
+
+	for i := int64(0); i < int64(attempts); i++ {
+		rand.Seed(seed + i)
+		maze := newMaze(grid, weaving)
+		distance := math.Abs(maze.analyze().Difficulty - target)
+		if distance < bestDistance {
+			best, bestSeed, bestDistance = maze, seed+i, distance
+		}
+	}
+
+	return best, bestSeed
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// handMaze builds a 3 by 3 maze by hand:
+//
+//	00 - 10   20
+//	|    |    |
+//	01 ==11== 21
+//	     |
+//	02 - 12 - 22
+//
+// with the passage from 01 to 21 running under the one through 11
+func handMaze() *Maze {
+	m := &Maze{grid: newGrid("square", 3, 3)}
+	m.cells = make([][]*Cell, 3)
+	for x := range m.cells {
+		m.cells[x] = make([]*Cell, 3)
+		for y := range m.cells[x] {
+			m.cells[x][y] = &Cell{dirs: map[Direction]bool{}}
+		}
+	}
+
+	link := func(x, y int, dir Direction) {
+		nx, ny, entry, _ := m.grid.neighbour(x, y, dir)
+		m.cells[x][y].dirs[dir] = true
+		m.cells[nx][ny].dirs[entry] = true
+	}
+	link(0, 0, dirE)
+	link(0, 0, dirS)
+	link(1, 0, dirS)
+	link(1, 1, dirS)
+	link(2, 0, dirS)
+	link(0, 2, dirE)
+	link(1, 2, dirE)
+
+	m.cells[0][1].dirs[dirE] = true
+	m.cells[2][1].dirs[dirW] = true
+	m.cells[1][1].weaved = true
+	m.cells[1][1].crossing = dirE
+	return m
+}
+
+func TestAnalyzeHandMaze(t *testing.T) {
+	m := handMaze()
+	if err := m.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := m.analyze()
+	want := Metrics{
+		Cells:           9,
+		DeadEnds:        3,       // 20, 02 and 22
+		BranchingFactor: 7.0 / 6, // 12 has two ways on, the other five passing cells one
+		RiverFactor:     8.0 / 3, // 20 runs six cells back to 12, 02 and 22 one each
+		LongestPath:     8,       // 20, 21, 01, 00, 10, 11, 12, 02
+		SolutionLength:  5,       // 00, 10, 11, 12, 22
+		Decisions:       1,       // At 12, towards 02
+		Weaves:          1,
+		// Coverage 5/9, a wrong turn in five cells and a crossing in nine
+		Difficulty: 100 * (5.0/9*0.45 + 0.4*0.4 + 5.0/9*0.15),
+	}
+
+	floats := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if got.Cells != want.Cells || got.DeadEnds != want.DeadEnds || got.LongestPath != want.LongestPath ||
+		got.SolutionLength != want.SolutionLength || got.Decisions != want.Decisions || got.Weaves != want.Weaves ||
+		!floats(got.BranchingFactor, want.BranchingFactor) || !floats(got.RiverFactor, want.RiverFactor) ||
+		!floats(got.Difficulty, want.Difficulty) {
+		t.Errorf("analyze() = %v\nwant %v", got, want)
+	}
+
+	solution := m.solution()
+	path := []point{{0, 0}, {1, 0}, {1, 1}, {1, 2}, {2, 2}}
+	if len(solution) != len(path) {
+		t.Fatalf("solution %v, want %v", solution, path)
+	}
+	for i := range path {
+		if solution[i] != path[i] {
+			t.Fatalf("solution %v, want %v", solution, path)
+		}
+	}
+}
+
+func TestAnalyzeCorridor(t *testing.T) {
+	// A single corridor is all solution, with no way to go wrong
+	m := &Maze{grid: newGrid("square", 4, 1), cells: [][]*Cell{
+		{{dirs: map[Direction]bool{dirE: true}}},
+		{{dirs: map[Direction]bool{dirW: true, dirE: true}}},
+		{{dirs: map[Direction]bool{dirW: true, dirE: true}}},
+		{{dirs: map[Direction]bool{dirW: true}}},
+	}}
+
+	got := m.analyze()
+	if got.DeadEnds != 2 || got.BranchingFactor != 1 || got.RiverFactor != 3 ||
+		got.LongestPath != 4 || got.SolutionLength != 4 || got.Decisions != 0 || got.Difficulty != 45 {
+		t.Errorf("analyze() = %v", got)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	startColor    = color.RGBA{131, 175, 155, 255}
+	finishColor   = color.RGBA{254, 67, 101, 255}
+	solutionColor = color.RGBA{254, 67, 101, 255}
+)
+
+// pdfTracer traces paths onto a PDF page,
+// scaled and moved into place on the page
+type pdfTracer struct {
+	pdf     *gofpdf.Fpdf
+	x, y    float64
+	scale   float64
+	current bool
+}
+
+func (t *pdfTracer) NewSubPath() {
+	t.current = false
+}
+
+func (t *pdfTracer) MoveTo(x, y float64) {
+	t.pdf.MoveTo(t.x+x*t.scale, t.y+y*t.scale)
+	t.current = true
+}
+
+func (t *pdfTracer) LineTo(x, y float64) {
+	if !t.current {
+		t.MoveTo(x, y)
+		return
+	}
+	t.pdf.LineTo(t.x+x*t.scale, t.y+y*t.scale)
+}
+
+func (t *pdfTracer) DrawArc(x, y, r, angle1, angle2 float64) {
+	const n = 16
+	for i := 0; i <= n; i++ {
+		a := angle1 + (angle2-angle1)*float64(i)/n
+		t.LineTo(x+r*math.Cos(a), y+r*math.Sin(a))
+	}
+}
+
+func (t *pdfTracer) DrawCircle(x, y, r float64) {
+	t.NewSubPath()
+	t.DrawArc(x, y, r, 0, 2*math.Pi)
+	t.pdf.ClosePath()
+}
+
+func (t *pdfTracer) DrawRegularPolygon(n int, x, y, r, rotation float64) {
+	angle := 2 * math.Pi / float64(n)
+	rotation -= math.Pi / 2
+	if n%2 == 0 {
+		rotation += angle / 2
+	}
+	t.NewSubPath()
+	for i := 0; i < n; i++ {
+		a := rotation + angle*float64(i)
+		t.LineTo(x+r*math.Cos(a), y+r*math.Sin(a))
+	}
+	t.pdf.ClosePath()
+}
+
+// stroke outlines everything traced so far
+func (t *pdfTracer) stroke(c color.Color, width float64) {
+	r, g, b, _ := c.RGBA()
+	t.pdf.SetDrawColor(int(r>>8), int(g>>8), int(b>>8))
+	t.pdf.SetLineWidth(width * t.scale)
+	t.pdf.DrawPath("D")
+	t.current = false
+}
+
+// fill fills everything traced so far
+func (t *pdfTracer) fill(c color.Color) {
+	r, g, b, _ := c.RGBA()
+	t.pdf.SetFillColor(int(r>>8), int(g>>8), int(b>>8))
+	t.pdf.DrawPath("F")
+	t.current = false
+}
+
+// printLevel prints the passages of a cell that share the same level,
+// with its core when the passages meet there
+func (t *pdfTracer) printLevel(s cellShape, dirs map[Direction]bool, core bool, width float64, rounded bool, c color.Color) {
+	if len(dirs) > 0 {
+		s.tracePassages(t, dirs)
+		t.stroke(c, width)
+	}
+
+	if core {
+		s.traceCore(t, width, rounded)
+		t.fill(c)
+	}
+}
+
+// printOn prints the maze on a PDF page, fitted into a square at x, y,
+// with markers where it starts and finishes, and its solution if asked to
+func (m *Maze) printOn(pdf *gofpdf.Fpdf, x, y, size float64, style Style, solved bool) {
+	// Cells are laid out on a canvas, which is then scaled to fit the page
+	const canvas = 1000.0
+	t := &pdfTracer{pdf: pdf, x: x, y: y, scale: size / canvas}
+	palette := style.Palette
+
+	r, g, b, _ := palette.Background.RGBA()
+	pdf.SetFillColor(int(r>>8), int(g>>8), int(b>>8))
+	pdf.Rect(x, y, size, size, "F")
+
+	pdf.SetLineCapStyle("butt")
+	pdf.SetLineJoinStyle("round")
+
+	level, crossings := m.placeCells(canvas, canvas)
+	if style.Walls > 0 {
+		for _, c := range level {
+			t.printLevel(c.shape, c.dirs, true, c.shape.size*(style.Corridor+style.Walls*2), style.Rounded, palette.Wall)
+		}
+	}
+	for _, c := range level {
+		t.printLevel(c.shape, c.dirs, true, c.shape.size*style.Corridor, style.Rounded, palette.Corridor)
+	}
+
+	// There are no gradients on paper, so crossings
+	// only stand out by their walls
+	for _, c := range crossings {
+		width := c.shape.size * style.Corridor
+		walls := c.shape.size * (style.Corridor + style.Walls*2)
+		over, under := c.levels()
+		// The core only belongs to the passages on top
+		for _, lv := range []struct {
+			dirs map[Direction]bool
+			core bool
+		}{{under, false}, {over, true}} {
+			if style.Walls > 0 {
+				t.printLevel(c.shape, lv.dirs, lv.core, walls, style.Rounded, palette.Wall)
+			}
+			t.printLevel(c.shape, lv.dirs, lv.core, width, style.Rounded, palette.Corridor)
+		}
+	}
+
+	pdf.SetLineCapStyle("round")
+	start, finish := m.ends()
+	if solved {
+		m.traceSolution(t, canvas)
+		t.stroke(solutionColor, m.grid.shape(start.x, start.y, canvas, canvas).size*style.Corridor*0.3)
+	}
+
+	for _, marker := range []struct {
+		p point
+		c color.Color
+	}{{start, startColor}, {finish, finishColor}} {
+		s := m.grid.shape(marker.p.x, marker.p.y, canvas, canvas)
+		t.DrawCircle(s.x, s.y, s.size*style.Corridor*0.35)
+		t.fill(marker.c)
+	}
+	pdf.SetLineCapStyle("butt")
+}
+
+// traceSolution traces the path from the start of the maze to its finish,
+// along the passages of each cell and across any crossings in between
+func (m *Maze) traceSolution(t tracer, canvas float64) {
+	path := m.solution()
+	for i, p := range path {
+		s := m.grid.shape(p.x, p.y, canvas, canvas)
+
+		for dir := range m.cells[p.x][p.y].dirs {
+			next, ok := m.follow(p, dir)
+			if !ok {
+				continue
+			}
+			if i > 0 && next == path[i-1] {
+				s.tracePassages(t, map[Direction]bool{dir: true})
+			}
+			if i == len(path)-1 || next != path[i+1] {
+				continue
+			}
+			s.tracePassages(t, map[Direction]bool{dir: true})
+
+			// Passages going under or over other cells are
+			// straight, so join up the two ends in a line
+			for back := range m.cells[next.x][next.y].dirs {
+				if prev, ok := m.follow(next, back); ok && prev == p {
+					to := m.grid.shape(next.x, next.y, canvas, canvas).exits[back]
+					t.NewSubPath()
+					t.MoveTo(s.exits[dir].x, s.exits[dir].y)
+					t.LineTo(to.x, to.y)
+				}
+			}
+		}
+	}
+}
+
+// printPuzzles lays out the mazes as printable puzzle sheets, a number of
+// them on every page, followed by answer pages with their solutions
+func printPuzzles(path string, mazes []*Maze, perPage int, style Style) error {
+	if perPage < 1 {
+		return fmt.Errorf("need at least one maze on every page, not %d", perPage)
+	}
+
+	const (
+		margin  = 15.0 // Around the page, in millimeters
+		gap     = 10.0 // Between mazes
+		heading = 12.0 // Room for the titles
+	)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Mazes", false)
+	pdf.SetAutoPageBreak(false, 0)
+	pageW, pageH := pdf.GetPageSize()
+
+	// Mazes are laid out in a grid, as close to square as it gets
+	cols := int(math.Ceil(math.Sqrt(float64(perPage))))
+	rows := (perPage + cols - 1) / cols
+	boxW := (pageW - margin*2 - gap*float64(cols-1)) / float64(cols)
+	boxH := (pageH - margin*2 - heading - gap*float64(rows-1)) / float64(rows)
+	size := math.Min(boxW, boxH-heading)
+
+	for _, solved := range []bool{false, true} {
+		title := "Mazes"
+		if solved {
+			title = "Answers"
+		}
+
+		for i, maze := range mazes {
+			if i%perPage == 0 {
+				pdf.AddPage()
+				pdf.SetFont("Helvetica", "B", 18)
+				pdf.SetTextColor(0, 0, 0)
+				pdf.Text(margin, margin+heading/2, title)
+			}
+
+			slot := i % perPage
+			x := margin + float64(slot%cols)*(boxW+gap) + (boxW-size)/2
+			y := margin + heading + float64(slot/cols)*(boxH+gap)
+
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.Text(x, y+heading/2, fmt.Sprintf("Maze %d", i+1))
+			maze.printOn(pdf, x, y+heading, size, style, solved)
+		}
+	}
+
+	return pdf.OutputFileAndClose(path)
+}
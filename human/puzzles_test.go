@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrintPuzzlesNeedsMazesOnEveryPage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "puzzles.pdf")
+	mazes := []*Maze{handMaze()}
+	for _, perPage := range []int{0, -1} {
+		if err := printPuzzles(path, mazes, perPage, Style{Palette: palettes["print"], Corridor: 0.6}); err == nil {
+			t.Errorf("%d mazes on every page printed fine", perPage)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("puzzles saved anyway: %v", err)
+	}
+
+	if err := printPuzzles(path, mazes, 1, Style{Palette: palettes["print"], Corridor: 0.6}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
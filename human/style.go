@@ -0,0 +1,272 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
+// Palette holds the colours a maze is drawn with
+type Palette struct {
+	Background color.Color
+	Corridor   color.Color
+	Highlight  color.Color // Top of the bridge where passages cross
+	Shade      color.Color // Bottom of the bridge where passages cross
+	Shadow     color.Color // Where passages disappear underneath others
+	Wall       color.Color
+}
+
+var palettes = map[string]Palette{
+	"heart": {
+		Background: color.RGBA{131, 175, 155, 255},
+		Corridor:   color.RGBA{249, 205, 173, 255},
+		Highlight:  color.RGBA{247, 217, 195, 255},
+		Shade:      color.RGBA{212, 164, 131, 255},
+		Shadow:     color.RGBA{184, 144, 116, 255},
+		Wall:       color.RGBA{254, 67, 101, 255},
+	},
+	"zen": {
+		Background: color.RGBA{63, 63, 63, 255},
+		Corridor:   color.RGBA{240, 223, 175, 255},
+		Highlight:  color.RGBA{239, 239, 239, 255},
+		Shade:      color.RGBA{220, 163, 163, 255},
+		Shadow:     color.RGBA{143, 175, 159, 255},
+		Wall:       color.RGBA{220, 163, 163, 255},
+	},
+	"terra": {
+		Background: color.RGBA{3, 22, 52, 255},
+		Corridor:   color.RGBA{232, 221, 203, 255},
+		Highlight:  color.RGBA{255, 250, 240, 255},
+		Shade:      color.RGBA{205, 179, 128, 255},
+		Shadow:     color.RGBA{3, 101, 100, 255},
+		Wall:       color.RGBA{3, 54, 73, 255},
+	},
+	"print": {
+		Background: color.White,
+		Corridor:   color.White,
+		Highlight:  color.White,
+		Shade:      color.Gray{224},
+		Shadow:     color.Gray{160},
+		Wall:       color.Black,
+	},
+	"blueprint": {
+		Background: color.RGBA{11, 72, 107, 255},
+		Corridor:   color.RGBA{11, 72, 107, 255},
+		Highlight:  color.RGBA{59, 134, 134, 255},
+		Shade:      color.RGBA{11, 50, 80, 255},
+		Shadow:     color.RGBA{5, 30, 50, 255},
+		Wall:       color.RGBA{207, 240, 158, 255},
+	},
+}
+
+// Style controls how a maze is drawn
+type Style struct {
+	Palette  Palette
+	Corridor float64 // Width of the corridors, from 0 to 1 of the widest that fits in a cell
+	Rounded  bool    // Round joints and dead ends, instead of square ones
+	Walls    float64 // When above zero, outline the corridors with walls this wide instead
+	Shadows  bool    // Cast a shadow from passages going over other passages
+}
+
+// tracer builds up paths, on a canvas or on a printed page
+type tracer interface {
+	NewSubPath()
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	DrawArc(x, y, r, angle1, angle2 float64)
+	DrawCircle(x, y, r float64)
+	DrawRegularPolygon(n int, x, y, r, rotation float64)
+}
+
+// cellShape is where a cell and its passages lie on the canvas
+type cellShape struct {
+	x, y     float64 // Middle of the cell
+	size     float64 // Widest a corridor can be and still fit in the cell
+	sides    int     // Sides of the cell's core, facing its exits
+	rotation float64 // Rotation of the cell's core
+	exits    map[Direction]exitShape
+}
+
+// exitShape is a passage from the middle of a cell to its edge
+type exitShape struct {
+	x, y  float64        // Where the passage leaves the cell
+	trace func(t tracer) // Traces the passage when it isn't a straight line
+}
+
+// sortedDirections returns the directions in a stable order,
+// so drawing doesn't depend on the order of map iteration
+func sortedDirections(dirs map[Direction]bool) []Direction {
+	sorted := []Direction{}
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// tracePassages traces the given passages of a cell
+func (s cellShape) tracePassages(t tracer, dirs map[Direction]bool) {
+	for _, dir := range sortedDirections(dirs) {
+		exit, ok := s.exits[dir]
+		if !ok {
+			continue
+		}
+
+		t.NewSubPath()
+		if exit.trace != nil {
+			exit.trace(t)
+			continue
+		}
+		t.MoveTo(s.x, s.y)
+		t.LineTo(exit.x, exit.y)
+	}
+}
+
+// traceCore traces the core of a cell, just as wide
+// as the corridors of the given width
+func (s cellShape) traceCore(t tracer, width float64, rounded bool) {
+	t.NewSubPath()
+	if rounded {
+		t.DrawCircle(s.x, s.y, width/2)
+		return
+	}
+	t.DrawRegularPolygon(s.sides, s.x, s.y, width/2/math.Cos(math.Pi/float64(s.sides)), s.rotation)
+}
+
+// drawLevel draws the passages of a cell that share the same level,
+// with its core when the passages meet there
+func (s cellShape) drawLevel(dc *gg.Context, dirs map[Direction]bool, core bool, width float64, rounded bool, stroke, fill gg.Pattern) {
+	if len(dirs) > 0 {
+		s.tracePassages(dc, dirs)
+		dc.SetStrokeStyle(stroke)
+		dc.SetLineWidth(width)
+		dc.Stroke()
+	}
+
+	if core {
+		s.traceCore(dc, width, rounded)
+		dc.SetFillStyle(fill)
+		dc.Fill()
+	}
+}
+
+// underpass fades a passage into the shadow as it goes under the cell's core
+func (s cellShape) underpass(dir Direction, width float64, palette Palette) gg.Pattern {
+	exit := s.exits[dir]
+	dx, dy := s.x-exit.x, s.y-exit.y
+	length := math.Hypot(dx, dy)
+	inset := (length - width/2) / length
+
+	grad := gg.NewLinearGradient(exit.x, exit.y, exit.x+dx*inset, exit.y+dy*inset)
+	grad.AddColorStop(0, palette.Corridor)
+	grad.AddColorStop(1, palette.Shadow)
+	return grad
+}
+
+// bridge lights up the core of a cell where it goes over other passages
+func (s cellShape) bridge(dirs map[Direction]bool, width float64, palette Palette) gg.Pattern {
+	// With nothing going over, there's no light to follow
+	if len(dirs) == 0 {
+		return gg.NewSolidPattern(palette.Corridor)
+	}
+
+	// The light follows the passage going over
+	dir := sortedDirections(dirs)[0]
+	exit := s.exits[dir]
+	dx, dy := exit.x-s.x, exit.y-s.y
+	length := math.Hypot(dx, dy)
+	dx, dy = dx/length*width/2, dy/length*width/2
+
+	grad := gg.NewLinearGradient(s.x+dx, s.y+dy, s.x-dx, s.y-dy)
+	grad.AddColorStop(0, palette.Corridor)
+	grad.AddColorStop(0.4, palette.Highlight)
+	grad.AddColorStop(0.6, palette.Shade)
+	grad.AddColorStop(1, palette.Corridor)
+	return grad
+}
+
+// translucent returns a colour at the given opacity, on top of its own.
+// The channels come premultiplied, so all of them fade together.
+func translucent(c color.Color, opacity float64) color.Color {
+	r, g, b, a := c.RGBA()
+	scale := func(v uint32) uint16 { return uint16(math.Round(float64(v) * opacity)) }
+	return color.RGBA64{scale(r), scale(g), scale(b), scale(a)}
+}
+
+// placedCell is a cell with its shape on the canvas
+type placedCell struct {
+	*Cell
+	shape cellShape
+}
+
+// placeCells lays out the cells of the maze on a canvas of the given size,
+// split between cells on a single level and crossings. Cells on a single
+// level go first, with all their walls underneath all their corridors
+// so that they join up. Crossings go on top, one level at a time.
+func (m *Maze) placeCells(w, h float64) ([]placedCell, []placedCell) {
+	level, crossings := []placedCell{}, []placedCell{}
+	for x := range m.cells {
+		for y, cell := range m.cells[x] {
+			placed := placedCell{cell, m.grid.shape(x, y, w, h)}
+			if cell.weaved {
+				crossings = append(crossings, placed)
+				continue
+			}
+			level = append(level, placed)
+		}
+	}
+	return level, crossings
+}
+
+func (m *Maze) drawOn(dc *gg.Context, style Style) {
+	w, h := float64(dc.Width()), float64(dc.Height())
+	palette := style.Palette
+	corridor := gg.NewSolidPattern(palette.Corridor)
+	wall := gg.NewSolidPattern(palette.Wall)
+
+	dc.Push()
+	defer dc.Pop()
+	dc.SetLineCapButt()
+	dc.SetLineJoinRound()
+
+	level, crossings := m.placeCells(w, h)
+	if style.Walls > 0 {
+		for _, c := range level {
+			width := c.shape.size * (style.Corridor + style.Walls*2)
+			c.shape.drawLevel(dc, c.dirs, true, width, style.Rounded, wall, wall)
+		}
+	}
+	for _, c := range level {
+		c.shape.drawLevel(dc, c.dirs, true, c.shape.size*style.Corridor, style.Rounded, corridor, corridor)
+	}
+
+	for _, c := range crossings {
+		width := c.shape.size * style.Corridor
+		walls := c.shape.size * (style.Corridor + style.Walls*2)
+		over, under := c.levels()
+
+		if style.Walls > 0 {
+			c.shape.drawLevel(dc, under, false, walls, style.Rounded, wall, nil)
+		}
+		for _, dir := range sortedDirections(under) {
+			c.shape.drawLevel(dc, map[Direction]bool{dir: true}, false, width, style.Rounded, c.shape.underpass(dir, width, palette), nil)
+		}
+
+		if style.Shadows {
+			shadow := gg.NewSolidPattern(translucent(palette.Shadow, 0.6))
+			offset := width / 8
+			dc.Push()
+			dc.Translate(offset, offset)
+			c.shape.drawLevel(dc, over, true, walls, style.Rounded, shadow, shadow)
+			dc.Pop()
+		}
+
+		if style.Walls > 0 {
+			c.shape.drawLevel(dc, over, true, walls, style.Rounded, wall, wall)
+		}
+		c.shape.drawLevel(dc, over, false, width, style.Rounded, corridor, nil)
+		c.shape.drawLevel(dc, nil, true, width, style.Rounded, nil, c.shape.bridge(over, width, palette))
+	}
+}
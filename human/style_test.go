@@ -0,0 +1,39 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestTranslucent(t *testing.T) {
+	for _, c := range []struct {
+		in      color.Color
+		opacity float64
+		want    color.NRGBA
+	}{
+		{color.NRGBA{200, 100, 50, 255}, 1, color.NRGBA{200, 100, 50, 255}},
+		{color.NRGBA{200, 100, 50, 255}, 0.5, color.NRGBA{200, 100, 50, 128}},
+		// Already see-through, the colour stays the same and fades further
+		{color.NRGBA{200, 100, 50, 128}, 0.5, color.NRGBA{200, 100, 50, 64}},
+		{color.NRGBA{200, 100, 50, 128}, 1, color.NRGBA{200, 100, 50, 128}},
+		{color.NRGBA{200, 100, 50, 255}, 0, color.NRGBA{}},
+	} {
+		got := color.NRGBAModel.Convert(translucent(c.in, c.opacity)).(color.NRGBA)
+		// A step either way, from rounding
+		diff := func(a, b uint8) bool { return int(a) > int(b)+1 || int(b) > int(a)+1 }
+		if diff(got.R, c.want.R) || diff(got.G, c.want.G) || diff(got.B, c.want.B) || diff(got.A, c.want.A) {
+			t.Errorf("translucent(%v, %v) = %v, want %v", c.in, c.opacity, got, c.want)
+		}
+	}
+}
+
+func TestBridgeWithNothingOnTop(t *testing.T) {
+	shape := newGrid("square", 3, 3).shape(1, 1, 300, 300)
+	palette := palettes["heart"]
+
+	// Without a passage going over, it's just the corridor
+	got := shape.bridge(map[Direction]bool{}, 10, palette).ColorAt(int(shape.x), int(shape.y))
+	if color.NRGBAModel.Convert(got) != color.NRGBAModel.Convert(palette.Corridor) {
+		t.Errorf("bridge with nothing over it is %v, want the corridor %v", got, palette.Corridor)
+	}
+}
@@ -0,0 +1,150 @@
+// Package layers stacks canvases of the same size, each with its own
+// opacity, blend mode and filters, and composites them into one image.
+package layers
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	stackblur "github.com/esimov/stackblur-go"
+	"github.com/fogleman/gg"
+)
+
+// Blend is how a layer's colours mix with the ones below it
+type Blend int
+
+const (
+	Normal Blend = iota
+	Add
+	Screen
+	Multiply
+	Overlay
+)
+
+// Blends has every blend mode by name
+var Blends = map[string]Blend{
+	"normal":   Normal,
+	"add":      Add,
+	"screen":   Screen,
+	"multiply": Multiply,
+	"overlay":  Overlay,
+}
+
+// mix blends a channel of the layer above into the one below,
+// both from 0 to 1
+func (b Blend) mix(below, above float64) float64 {
+	switch b {
+	case Add:
+		return math.Min(1, below+above)
+	case Screen:
+		return below + above - below*above
+	case Multiply:
+		return below * above
+	case Overlay:
+		if below < 0.5 {
+			return 2 * below * above
+		}
+		return 1 - 2*(1-below)*(1-above)
+	}
+	return above
+}
+
+// Filter changes the image of a layer before it's composited
+type Filter func(img image.Image) image.Image
+
+// Blur blurs the layer, to make it glow
+func Blur(radius uint32) Filter {
+	return func(img image.Image) image.Image {
+		return stackblur.Process(img, radius)
+	}
+}
+
+// layer is a canvas of its own, drawn onto the ones below it
+type layer struct {
+	name    string
+	dc      *gg.Context
+	opacity float64
+	blend   Blend
+	filters []Filter
+}
+
+// Stack is a stack of canvases, all the same size,
+// composited from the bottom up over a background
+type Stack struct {
+	width, height int
+	background    color.Color
+	layers        []*layer
+}
+
+func New(width, height int, background color.Color) *Stack {
+	return &Stack{width: width, height: height, background: background}
+}
+
+// Add puts a new layer on top of the others, and returns its canvas
+func (s *Stack) Add(name string, opacity float64, blend Blend, filters ...Filter) *gg.Context {
+	l := &layer{name, gg.NewContext(s.width, s.height), opacity, blend, filters}
+	s.layers = append(s.layers, l)
+	return l.dc
+}
+
+// Layer returns the canvas of the named layer, if there's one
+func (s *Stack) Layer(name string) (*gg.Context, bool) {
+	for _, l := range s.layers {
+		if l.name == name {
+			return l.dc, true
+		}
+	}
+	return nil, false
+}
+
+// Composite filters every layer, and draws them all one over another
+func (s *Stack) Composite() image.Image {
+	bounds := image.Rect(0, 0, s.width, s.height)
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, image.NewUniform(s.background), image.Point{}, draw.Src)
+
+	for _, l := range s.layers {
+		var img image.Image = l.dc.Image()
+		for _, f := range l.filters {
+			img = f(img)
+		}
+
+		if l.blend == Normal {
+			mask := image.NewUniform(color.Alpha{uint8(math.Round(l.opacity * 255))})
+			draw.DrawMask(out, bounds, img, img.Bounds().Min, mask, image.Point{}, draw.Over)
+			continue
+		}
+
+		src := image.NewNRGBA(bounds)
+		draw.Draw(src, bounds, img, img.Bounds().Min, draw.Src)
+		blendOnto(out, src, l.opacity, l.blend)
+	}
+	return out
+}
+
+// blendOnto draws the layer over the image, blending where the two
+// overlap and falling back on the layer's own colours where nothing's below
+func blendOnto(dst *image.RGBA, src *image.NRGBA, opacity float64, blend Blend) {
+	for i := 0; i < len(src.Pix); i += 4 {
+		as := float64(src.Pix[i+3]) / 255 * opacity
+		if as == 0 {
+			continue
+		}
+		ab := float64(dst.Pix[i+3]) / 255
+		ao := as + ab*(1-as)
+
+		for c := 0; c < 3; c++ {
+			cs := float64(src.Pix[i+c]) / 255
+			// Below is premultiplied
+			cb := 0.0
+			if ab > 0 {
+				cb = float64(dst.Pix[i+c]) / 255 / ab
+			}
+			mixed := (1-ab)*cs + ab*blend.mix(cb, cs)
+			dst.Pix[i+c] = uint8(math.Round((as*mixed + ab*cb*(1-as)) * 255))
+		}
+		dst.Pix[i+3] = uint8(math.Round(ao * 255))
+	}
+}
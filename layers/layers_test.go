@@ -0,0 +1,81 @@
+package layers
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+// composite blends one colour over another, on a single pixel
+func composite(below, above color.RGBA, opacity float64, blend Blend) color.RGBA {
+	s := New(1, 1, below)
+	dc := s.Add("above", opacity, blend)
+	dc.SetColor(above)
+	dc.Clear()
+	return s.Composite().At(0, 0).(color.RGBA)
+}
+
+func TestBlends(t *testing.T) {
+	// 0.2, 0.4, 0.8 below and 0.6, 0.2, 1 above
+	below, above := color.RGBA{51, 102, 204, 255}, color.RGBA{153, 51, 255, 255}
+	// All of one below, half of one and none of one, under half and all of one
+	full, half := color.RGBA{255, 128, 0, 255}, color.RGBA{128, 128, 255, 255}
+
+	for _, c := range []struct {
+		name         string
+		below, above color.RGBA
+		opacity      float64
+		want         color.RGBA
+	}{
+		{"normal", below, above, 1, above},
+		{"add", below, above, 1, color.RGBA{204, 153, 255, 255}},
+		{"add", full, half, 1, color.RGBA{255, 255, 255, 255}},
+		{"screen", below, above, 1, color.RGBA{173, 133, 255, 255}},
+		{"screen", full, half, 1, color.RGBA{255, 192, 255, 255}},
+		{"multiply", below, above, 1, color.RGBA{31, 20, 204, 255}},
+		{"multiply", full, half, 1, color.RGBA{128, 64, 0, 255}},
+		{"overlay", below, above, 1, color.RGBA{61, 41, 255, 255}},
+		{"overlay", full, half, 1, color.RGBA{255, 128, 0, 255}},
+		// Halfway between the screened colour and the one below
+		{"screen", below, above, 0.5, color.RGBA{112, 117, 230, 255}},
+		{"multiply", below, above, 0, below},
+	} {
+		if got := composite(c.below, c.above, c.opacity, Blends[c.name]); got != c.want {
+			t.Errorf("%s of %v over %v at %v = %v, want %v", c.name, c.above, c.below, c.opacity, got, c.want)
+		}
+	}
+}
+
+func TestBlendOverNothing(t *testing.T) {
+	// With nothing below, every blend leaves the layer as it is
+	above := color.RGBA{153, 51, 255, 255}
+	for name, blend := range Blends {
+		if got := composite(color.RGBA{}, above, 1, blend); got != above {
+			t.Errorf("%s over nothing = %v, want %v", name, got, above)
+		}
+	}
+}
+
+func TestLayerByName(t *testing.T) {
+	s := New(2, 2, color.Black)
+	lines := s.Add("lines", 1, Normal)
+	glow := s.Add("glow", 0.5, Screen)
+
+	for name, want := range map[string]*gg.Context{"lines": lines, "glow": glow} {
+		if got, ok := s.Layer(name); !ok || got != want {
+			t.Errorf("Layer(%q) = %p, %v, want %p", name, got, ok, want)
+		}
+	}
+	if got, ok := s.Layer("egos"); ok || got != nil {
+		t.Errorf("Layer(\"egos\") = %p, %v, want none", got, ok)
+	}
+
+	// Drawing on the layer found by name shows in the composite
+	dc, _ := s.Layer("lines")
+	dc.SetColor(color.White)
+	dc.Clear()
+	if got := s.Composite().At(0, 0).(color.RGBA); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("composite %v, want white", got)
+	}
+}
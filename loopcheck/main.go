@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// loopcheck reports how far the last frame of an animation is from leading
+// back into the first, next to how far it usually goes from one frame
+// to the next, for the frames the sketches save or for a GIF, like:
+//
+//	loopcheck -frames i-%d.png
+//	loopcheck animation.gif
+func main() {
+	pattern := flag.String("frames", "i-%d.png", "frames of the animation, numbered from 0")
+	max := flag.Float64("max", 1.5, "fail when the loop jumps more than this many times the usual step")
+	block := flag.Int("block", 16, "compare the average colours of blocks this many pixels across")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [animation.gif]\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var first, previous *image.RGBA
+	steps := []float64{}
+	visit := func(frame *image.RGBA) error {
+		frame = shrink(frame, *block)
+		if first == nil {
+			first, previous = frame, frame
+			return nil
+		}
+		step, err := difference(previous, frame)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", len(steps)+1, err)
+		}
+		steps = append(steps, step)
+		previous = frame
+		return nil
+	}
+
+	var err error
+	switch flag.NArg() {
+	case 0:
+		err = sequence(*pattern, visit)
+	case 1:
+		err = gifFrames(flag.Arg(0), visit)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(steps) == 0 {
+		log.Fatal("need at least two frames to loop")
+	}
+
+	jump, err := difference(previous, first)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The middle step, so a few big jumps or still frames don't sway it
+	sorted := append([]float64{}, steps...)
+	sort.Float64s(sorted)
+	usual := sorted[len(sorted)/2]
+
+	fmt.Printf("frames      %d\n", len(steps)+1)
+	fmt.Printf("loop error  %.5f  from the last frame back to the first\n", jump)
+	fmt.Printf("usual step  %.5f  from one frame to the next\n", usual)
+	if usual == 0 {
+		if jump > 0 {
+			log.Fatal("the frames stand still, and then jump")
+		}
+		return
+	}
+	fmt.Printf("ratio       %.2f\n", jump/usual)
+	if jump/usual > *max {
+		log.Fatalf("the loop jumps %.2f times as far as the usual step, more than %.2f", jump/usual, *max)
+	}
+}
+
+// difference is how far apart two frames are, as the mean difference
+// of their red, green and blue, from 0 for the same to 1
+func difference(a, b *image.RGBA) (float64, error) {
+	if a.Rect != b.Rect {
+		return 0, fmt.Errorf("frames of different sizes, %v and %v", a.Rect, b.Rect)
+	}
+	total := 0
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		pa := a.Pix[a.PixOffset(a.Rect.Min.X, y):a.PixOffset(a.Rect.Max.X, y)]
+		pb := b.Pix[b.PixOffset(b.Rect.Min.X, y):b.PixOffset(b.Rect.Max.X, y)]
+		for i := 0; i < len(pa); i += 4 {
+			for c := 0; c < 3; c++ {
+				d := int(pa[i+c]) - int(pb[i+c])
+				if d < 0 {
+					d = -d
+				}
+				total += d
+			}
+		}
+	}
+	return float64(total) / float64(a.Rect.Dx()*a.Rect.Dy()*3*255), nil
+}
+
+// shrink averages the colours of every block of pixels. Small things
+// that move further than they're big differ as much wherever they end up,
+// while in blocks they differ more the further they go.
+func shrink(img *image.RGBA, block int) *image.RGBA {
+	if block <= 1 {
+		return img
+	}
+	b := img.Rect
+	out := image.NewRGBA(image.Rect(0, 0, (b.Dx()+block-1)/block, (b.Dy()+block-1)/block))
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			sum, n := [4]int{}, 0
+			for by := y * block; by < (y+1)*block && by < b.Dy(); by++ {
+				for bx := x * block; bx < (x+1)*block && bx < b.Dx(); bx++ {
+					i := img.PixOffset(b.Min.X+bx, b.Min.Y+by)
+					for c := range sum {
+						sum[c] += int(img.Pix[i+c])
+					}
+					n++
+				}
+			}
+			i := out.PixOffset(x, y)
+			for c := range sum {
+				out.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return out
+}
+
+// sequence visits the frames from the pattern, numbered from 0,
+// until there's no next one
+func sequence(pattern string, visit func(*image.RGBA) error) error {
+	if !strings.Contains(pattern, "%") {
+		return fmt.Errorf("%q has no %%d for the frame number", pattern)
+	}
+	for i := 0; ; i++ {
+		f, err := os.Open(fmt.Sprintf(pattern, i))
+		if errors.Is(err, fs.ErrNotExist) && i > 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		if err := visit(rgba(img)); err != nil {
+			return err
+		}
+	}
+}
+
+// gifFrames visits the frames of a GIF as they're shown, every one
+// drawn over what's left of the ones before
+func gifFrames(path string, visit func(*image.RGBA) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for i, frame := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		before := canvas
+		if disposal == gif.DisposalPrevious {
+			before = rgba(canvas)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if err := visit(rgba(canvas)); err != nil {
+			return err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = before
+		}
+	}
+	return nil
+}
+
+// rgba copies an image, from 0, 0
+func rgba(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+	return out
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"log"
+	"log/slog"
 	"math"
 	"math/rand"
 
 	"github.com/anthonynsimon/bild/paint"
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
@@ -65,11 +68,15 @@ func main() {
 
 		angleStart = 110.0 // Start angle
 		angleEnd   = 190.0 // End angle
+
+		logLevel = slog.LevelInfo // slog.LevelWarn to leave out the progress
 	)
+	report.Log(logLevel)
 
 	// A list of themes to use for the composite
 	// Must have N or more items, where N is the product of X*Y from the composite code
 	makeThemes := []string{"japanese-lovers", "cake", "compatible", "goldfish", "cheer-emo", "melon"}
+	progress := report.NewProgress(len(makeThemes))
 	for _, theme := range makeThemes {
 		angle := angleStart
 		stepAngle := (angle - angleEnd) / lines
@@ -127,7 +134,10 @@ func main() {
 			}
 		}
 
-		gg.SavePNG(fmt.Sprintf("out-%s.png", theme), img)
+		if err := gg.SavePNG(fmt.Sprintf("out-%s.png", theme), img); err != nil {
+			log.Fatal(err)
+		}
+		progress.Frame()
 	}
 
 	// Now, create the composite, choosing the matrix size
@@ -151,5 +161,8 @@ func main() {
 			dc.DrawImage(im, x*s, y*s)
 		}
 	}
-	dc.SavePNG("composite.png")
+	if err := dc.SavePNG("composite.png"); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("saved", "path", "composite.png")
 }
@@ -2,8 +2,11 @@ package main
 
 import (
 	"image/color"
+	"log"
+	"log/slog"
 	"math/rand"
 
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
@@ -25,7 +28,10 @@ func main() {
 
 		lines = 300.0  // Number of lines to draw
 		angle = -120.0 //
+
+		logLevel = slog.LevelInfo // slog.LevelWarn to leave out where the image went
 	)
+	report.Log(logLevel)
 
 	// Calculate the radius based on the radius and padding
 	r := s/2.0 - padding
@@ -58,5 +64,8 @@ func main() {
 		dc.Stroke()
 	}
 
-	dc.SavePNG("output.png")
+	if err := dc.SavePNG("output.png"); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("saved", "path", "output.png")
 }
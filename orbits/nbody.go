@@ -0,0 +1,183 @@
+package orbits
+
+import (
+	"log/slog"
+	"math"
+)
+
+// body is an ego with mass, pulled by every other one and by the mass
+// at the center, instead of travelling an orbit set from the start
+type body struct {
+	x, y, vx, vy, ax, ay float64 // From the center, in pixels and frames
+	mass, radius         float64
+}
+
+// merge is a body that ran into another one and became part of it,
+// or into the center when into is -1
+type merge struct {
+	into, from int
+}
+
+// Physics is how hard egos pull on each other, and on a mass at the center
+type Physics struct {
+	Gravity     float64 // How hard a unit of mass pulls, in pixels³ per frame²
+	Softening   float64 // Pulls ease off closer than this, instead of flinging egos away. It's how big the center is, too.
+	CentralMass float64 // Mass fixed at the center, if any
+	EgoMass     float64
+	Merge       bool // Whether egos that run into each other become one
+	Substeps    int  // Steps of the simulation in every frame
+}
+
+// NewPhysics returns the physics every sketch starts from, with pulls
+// as hard as gravity, easing off closer than softening, around a center
+// of the given mass, and every ego weighing 1
+func NewPhysics(gravity, softening, centralMass float64, merge bool) Physics {
+	return Physics{Gravity: gravity, Softening: softening, CentralMass: centralMass, EgoMass: 1, Merge: merge, Substeps: 8}
+}
+
+// Nbody moves bodies by their gravity, with a leapfrog integrator,
+// which keeps orbits from slowly spiralling in or flying apart
+type Nbody struct {
+	Physics
+	bodies []body
+}
+
+func NewNbody(p Physics) *Nbody {
+	return &Nbody{Physics: p}
+}
+
+// Add puts a body at rest at x, y from the center
+func (n *Nbody) Add(x, y, radius float64) {
+	n.bodies = append(n.bodies, body{x: x, y: y, mass: n.EgoMass, radius: radius})
+}
+
+// At returns where the ith body is, from the center
+func (n *Nbody) At(i int) (float64, float64) {
+	return n.bodies[i].x, n.bodies[i].y
+}
+
+// Launch sets every body going around the center, as fast as it takes
+// to stay as far from it, given the pull of everything else
+func (n *Nbody) Launch() {
+	n.accelerate()
+	for i := range n.bodies {
+		b := &n.bodies[i]
+		r := math.Hypot(b.x, b.y)
+		if r == 0 {
+			continue
+		}
+		// Pulled in by a, it takes v² = a r to go around,
+		// and a r is how much of a points along -r, times r
+		v := math.Sqrt(math.Max(0, -(b.ax*b.x + b.ay*b.y)))
+		b.vx, b.vy = -b.y/r*v, b.x/r*v
+	}
+}
+
+// Step moves the bodies along the given frame, and returns the bodies
+// that merged into others along the way, in the order they did, each one
+// numbered as it was right then. Taking them out of a list in that order
+// keeps it in step with the bodies.
+func (n *Nbody) Step(frame int) []int {
+	gone := []int{}
+	for _, m := range n.step() {
+		slog.Debug("merged", "frame", frame, "into", m.into, "from", m.from)
+		gone = append(gone, m.from)
+	}
+	return gone
+}
+
+// step moves the bodies along one frame, in smaller steps, and returns
+// the merges along the way
+func (n *Nbody) step() []merge {
+	merges := []merge{}
+	dt := 1 / float64(n.Substeps)
+	for i := 0; i < n.Substeps; i++ {
+		// Kick half a step, drift a whole one, and kick the other half
+		// with the pull from where the bodies end up
+		n.kick(dt / 2)
+		for j := range n.bodies {
+			b := &n.bodies[j]
+			b.x += b.vx * dt
+			b.y += b.vy * dt
+		}
+		if n.Merge {
+			merges = append(merges, n.collide()...)
+		}
+		n.accelerate()
+		n.kick(dt / 2)
+	}
+	return merges
+}
+
+func (n *Nbody) kick(dt float64) {
+	for i := range n.bodies {
+		b := &n.bodies[i]
+		b.vx += b.ax * dt
+		b.vy += b.ay * dt
+	}
+}
+
+// accelerate works out the pull on every body, from every other one
+// and from the center
+func (n *Nbody) accelerate() {
+	soft := n.Softening * n.Softening
+	pull := func(dx, dy float64) float64 {
+		return n.Gravity / math.Pow(dx*dx+dy*dy+soft, 1.5)
+	}
+
+	for i := range n.bodies {
+		b := &n.bodies[i]
+		f := pull(b.x, b.y) * n.CentralMass
+		b.ax, b.ay = -b.x*f, -b.y*f
+	}
+	for i := range n.bodies {
+		for j := i + 1; j < len(n.bodies); j++ {
+			a, b := &n.bodies[i], &n.bodies[j]
+			dx, dy := b.x-a.x, b.y-a.y
+			f := pull(dx, dy)
+			a.ax += dx * f * b.mass
+			a.ay += dy * f * b.mass
+			b.ax -= dx * f * a.mass
+			b.ay -= dy * f * a.mass
+		}
+	}
+}
+
+// collide merges the bodies that touch, keeping their mass and momentum,
+// and lets the center swallow the ones that fall into it
+func (n *Nbody) collide() []merge {
+	merges := []merge{}
+	for i := 0; i < len(n.bodies); i++ {
+		b := &n.bodies[i]
+		if n.CentralMass > 0 && math.Hypot(b.x, b.y) < n.Softening {
+			n.CentralMass += b.mass
+			n.remove(i)
+			merges = append(merges, merge{-1, i})
+			i--
+			continue
+		}
+
+		for j := i + 1; j < len(n.bodies); j++ {
+			o := n.bodies[j]
+			if math.Hypot(o.x-b.x, o.y-b.y) >= b.radius+o.radius {
+				continue
+			}
+			mass := b.mass + o.mass
+			b.x = (b.x*b.mass + o.x*o.mass) / mass
+			b.y = (b.y*b.mass + o.y*o.mass) / mass
+			b.vx = (b.vx*b.mass + o.vx*o.mass) / mass
+			b.vy = (b.vy*b.mass + o.vy*o.mass) / mass
+			b.mass = mass
+			// As big as both of them put together
+			b.radius = math.Hypot(b.radius, o.radius)
+			n.remove(j)
+			merges = append(merges, merge{i, j})
+			j--
+		}
+	}
+	return merges
+}
+
+func (n *Nbody) remove(i int) {
+	n.bodies = append(n.bodies[:i], n.bodies[i+1:]...)
+}
@@ -0,0 +1,56 @@
+package orbits
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLaunchKeepsBodiesGoingAround(t *testing.T) {
+	n := NewNbody(NewPhysics(5, 10, 100, true))
+	n.Add(0, -200, 5)
+	n.Launch()
+
+	// Around and around, without falling in or flying off
+	turned := 0.0
+	for frame := 0; frame < 2000; frame++ {
+		x, y := n.At(0)
+		n.Step(frame)
+		nx, ny := n.At(0)
+		turned += math.Atan2(x*ny-y*nx, x*nx+y*ny)
+		if r := math.Hypot(nx, ny); math.Abs(r-200) > 2 {
+			t.Fatalf("frame %d: %v from the center, want about 200", frame, r)
+		}
+	}
+	if turned < 2*math.Pi {
+		t.Errorf("went %v of the way around, want at least once", turned/2/math.Pi)
+	}
+}
+
+func TestStepMergesBodies(t *testing.T) {
+	p := NewPhysics(5, 10, 0, true)
+	n := NewNbody(p)
+	n.Add(0, 100, 5)
+	n.Add(300, 0, 5)
+	n.Add(6, 100, 5)
+	n.bodies[0].vx, n.bodies[2].vx = 3, -1
+
+	// The first and the last touch, and become one in the middle
+	// of them, going as fast as both of them together
+	if gone := n.Step(0); len(gone) != 1 || gone[0] != 2 {
+		t.Fatalf("Step() = %v, want [2]", gone)
+	}
+	if len(n.bodies) != 2 || n.bodies[0].mass != 2 {
+		t.Fatalf("%d bodies, the first with mass %v, want 2 and 2", len(n.bodies), n.bodies[0].mass)
+	}
+	if vx := n.bodies[0].vx; math.Abs(vx-1) > 0.01 {
+		t.Errorf("merged body going %v, want about 1", vx)
+	}
+
+	// Into the center when there's one
+	p.CentralMass = 100
+	n = NewNbody(p)
+	n.Add(5, 0, 5)
+	if gone := n.Step(0); len(gone) != 1 || gone[0] != 0 || n.CentralMass != 101 {
+		t.Errorf("Step() = %v with a center of %v, want [0] and 101", gone, n.CentralMass)
+	}
+}
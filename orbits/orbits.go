@@ -0,0 +1,208 @@
+// Package orbits has the paths egos travel around the center, and the
+// gravity that pulls them off those paths.
+package orbits
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/fogleman/gg"
+)
+
+// Orbit is the path an ego travels along
+type Orbit interface {
+	// At returns where the ego is, from the center of the orbit,
+	// once it's travelled the given angle, in degrees
+	At(angle float64) (float64, float64)
+	// Period is how far an ego travels before it's back where it started,
+	// or 0 if it never is
+	Period() float64
+}
+
+// circle is travelled at the same speed all the way around
+type circle struct {
+	radius float64
+}
+
+func (c circle) At(angle float64) (float64, float64) {
+	sin, cos := math.Sincos(gg.Radians(angle))
+	return c.radius * cos, c.radius * sin
+}
+
+func (c circle) Period() float64 { return 360 }
+
+// Circle returns a circle of the given radius around the center
+func Circle(radius float64) Orbit {
+	return circle{radius}
+}
+
+// rotate turns a point around the center, by degrees
+func rotate(x, y, degrees float64) (float64, float64) {
+	sin, cos := math.Sincos(gg.Radians(degrees))
+	return x*cos - y*sin, x*sin + y*cos
+}
+
+// ellipse is centred on the center, with its long axis
+// turned by rotation, and travelled like a squashed circle
+type ellipse struct {
+	a, eccentricity, rotation float64 // a is half the long axis
+}
+
+func (e ellipse) At(angle float64) (float64, float64) {
+	sin, cos := math.Sincos(gg.Radians(angle))
+	b := e.a * math.Sqrt(1-e.eccentricity*e.eccentricity)
+	return rotate(e.a*cos, b*sin, e.rotation)
+}
+
+func (e ellipse) Period() float64 { return 360 }
+
+// kepler is an ellipse with the center at one of its foci, travelled
+// like a planet around the sun: fast up close, and slow far away.
+// The angle travelled is what astronomers call the mean anomaly.
+type kepler struct {
+	a, eccentricity, rotation float64
+}
+
+func (k kepler) At(angle float64) (float64, float64) {
+	// Kepler's equation, M = E - e sin E, solved for E by Newton's method
+	m := gg.Radians(angle)
+	e := m
+	for i := 0; i < 8; i++ {
+		e -= (e - k.eccentricity*math.Sin(e) - m) / (1 - k.eccentricity*math.Cos(e))
+	}
+
+	sin, cos := math.Sincos(e)
+	b := k.a * math.Sqrt(1-k.eccentricity*k.eccentricity)
+	return rotate(k.a*(cos-k.eccentricity), b*sin, k.rotation)
+}
+
+func (k kepler) Period() float64 { return 360 }
+
+// precessing turns another orbit around the center as it's travelled,
+// by rate degrees for every degree along it
+type precessing struct {
+	orbit Orbit
+	rate  float64
+}
+
+func (p precessing) At(angle float64) (float64, float64) {
+	x, y := p.orbit.At(angle)
+	return rotate(x, y, angle*p.rate)
+}
+
+func (p precessing) Period() float64 { return 0 }
+
+// epicycle travels an orbit around a point that travels an orbit of its own,
+// ratio times as fast, backwards when the ratio is negative
+type epicycle struct {
+	carrier, orbit Orbit
+	ratio          float64
+}
+
+func (e epicycle) At(angle float64) (float64, float64) {
+	cx, cy := e.carrier.At(angle)
+	x, y := e.orbit.At(angle * e.ratio)
+	return cx + x, cy + y
+}
+
+func (e epicycle) Period() float64 {
+	if e.ratio != math.Trunc(e.ratio) || e.carrier.Period() != 360 || e.orbit.Period() != 360 {
+		return 0
+	}
+	return 360
+}
+
+// loopTurns rounds how many times a speed goes around the orbit over the
+// frames to a whole number, at least once
+func loopTurns(o Orbit, speed float64, frames int) (float64, error) {
+	p := o.Period()
+	if p == 0 {
+		return 0, fmt.Errorf("%T orbits never come back around, so they can't loop", o)
+	}
+	return math.Max(1, math.Round(speed*float64(frames)/p)), nil
+}
+
+// LoopSpeeds rounds every speed to the nearest one that goes around its
+// orbit a whole number of times over the frames, at least once, so the
+// last frame leads straight back into the first. When they'd all go
+// around as many times as each other, and turn together like a wheel,
+// they go around from once up to most times instead, one after another.
+func LoopSpeeds(orbits []Orbit, speeds []float64, frames, most int) ([]float64, error) {
+	turns := make([]float64, len(orbits))
+	for i, o := range orbits {
+		var err error
+		if turns[i], err = loopTurns(o, speeds[i], frames); err != nil {
+			return nil, err
+		}
+	}
+
+	together := len(turns) > 1
+	for _, t := range turns {
+		together = together && t == turns[0]
+	}
+	if together {
+		if most < 2 {
+			return nil, fmt.Errorf("all %d orbits go around %v times together, and can't go around up to %d times instead", len(turns), turns[0], most)
+		}
+		for i := range turns {
+			turns[i] = float64(1 + i%most)
+		}
+	}
+
+	looped := make([]float64, len(orbits))
+	for i, o := range orbits {
+		looped[i] = turns[i] * o.Period() / float64(frames)
+	}
+	return looped, nil
+}
+
+// Kinds are the kinds of orbits New can make
+var Kinds = []string{"circle", "ellipse", "kepler", "precessing", "epicycle"}
+
+// New returns an orbit of the given kind, reaching about
+// as far from the center as the radius, with the rest left to chance
+func New(kind string, radius float64) (Orbit, error) {
+	// Circles take nothing from chance, so they travel as they always have
+	planet := func() kepler {
+		e := 0.2 + rand.Float64()*0.6
+		// At its farthest, the orbit is a(1 + e) away
+		return kepler{radius / (1 + e), e, rand.Float64() * 360}
+	}
+
+	switch kind {
+	case "circle":
+		return circle{radius}, nil
+	case "ellipse":
+		return ellipse{radius, 0.2 + rand.Float64()*0.6, rand.Float64() * 360}, nil
+	case "kepler":
+		return planet(), nil
+	case "precessing":
+		return precessing{planet(), 0.05 + rand.Float64()*0.25}, nil
+	case "epicycle":
+		ratio := float64(2 + rand.Intn(6))
+		if rand.Intn(2) == 0 {
+			ratio = -ratio
+		}
+		small := radius * (0.15 + rand.Float64()*0.25)
+		return epicycle{circle{radius - small}, circle{small}, ratio}, nil
+	}
+	return nil, fmt.Errorf("no orbit named %q, try one of %v", kind, Kinds)
+}
+
+// Trace traces the path of an orbit around the center, all the way
+// around when it comes back around, however little of it's travelled,
+// or otherwise from one angle travelled to another
+func Trace(dc *gg.Context, o Orbit, cx, cy, from, to float64) {
+	if p := o.Period(); p > 0 {
+		to = from + p
+	}
+
+	const step = 0.5 // Degrees between points along the path
+	steps := int(math.Max(1, math.Ceil((to-from)/step)))
+	dc.NewSubPath()
+	for i := 0; i <= steps; i++ {
+		x, y := o.At(from + (to-from)*float64(i)/float64(steps))
+		dc.LineTo(cx+x, cy+y)
+	}
+}
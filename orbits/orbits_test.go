@@ -0,0 +1,98 @@
+package orbits
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestLoopSpeedsComeBackAround(t *testing.T) {
+	rand.Seed(1)
+	const frames = 360
+
+	for _, kind := range []string{"circle", "ellipse", "kepler", "epicycle"} {
+		paths, speeds := []Orbit{}, []float64{}
+		for i := 0; i < 20; i++ {
+			o, err := New(kind, 75+rand.Float64()*350)
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths, speeds = append(paths, o), append(speeds, 0.05+rand.Float64()*6)
+		}
+		looped, err := LoopSpeeds(paths, speeds, frames, 3)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+
+		// The frame after the last is where the first one was
+		for i, o := range paths {
+			start := rand.Float64() * 180
+			x, y := o.At(start)
+			ex, ey := o.At(start + looped[i]*frames)
+			if d := math.Hypot(ex-x, ey-y); d > 1e-6 {
+				t.Errorf("%s at speed %v is %v off after %d frames", kind, looped[i], d, frames)
+			}
+		}
+	}
+}
+
+func TestLoopSpeedsRound(t *testing.T) {
+	for _, c := range []struct{ speed, want float64 }{
+		{0.01, 1},  // Never less than once around
+		{1.2, 1},   // 432 degrees, closest to once around
+		{1.6, 2},   // 576 degrees, closest to twice around
+		{2.75, 3},  // 990 degrees, closest to three times around
+		{3, 3},     // Already whole
+		{6.49, 6},  // 2336.4 degrees, closest to six times around
+		{6.51, 7},  // 2343.6 degrees, closest to seven times around
+		{20.1, 20}, // Fast ones too
+	} {
+		got, err := LoopSpeeds([]Orbit{circle{100}}, []float64{c.speed}, 360, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[0] != c.want {
+			t.Errorf("LoopSpeeds(%v) = %v, want %v", c.speed, got[0], c.want)
+		}
+	}
+
+	if _, err := LoopSpeeds([]Orbit{circle{100}, precessing{circle{100}, 0.1}}, []float64{1, 1}, 360, 3); err == nil {
+		t.Error("precessing orbits don't come back around, but LoopSpeeds gave no error")
+	}
+}
+
+func TestLoopSpeedsSpreadEgosGoingAroundTogether(t *testing.T) {
+	paths := []Orbit{circle{100}, circle{200}, circle{300}, circle{400}, circle{500}}
+
+	// Slow ones all go around once, so they go around
+	// once, twice, three times, and once and twice again
+	got, err := LoopSpeeds(paths, []float64{0.05, 0.3, 0.6, 0.2, 0.1}, 360, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{1, 2, 3, 1, 2} {
+		if got[i] != want {
+			t.Errorf("speeds %v, want ego %d going around %v times", got, i, want)
+		}
+	}
+
+	// Any two going around a different number of times is enough
+	got, err = LoopSpeeds(paths, []float64{0.05, 0.3, 0.6, 0.2, 1.6}, 360, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{1, 1, 1, 1, 2} {
+		if got[i] != want {
+			t.Errorf("speeds %v, want ego %d going around %v times", got, i, want)
+		}
+	}
+
+	// With nowhere to spread them, it's an error
+	if _, err := LoopSpeeds(paths, []float64{0.05, 0.3, 0.6, 0.2, 0.1}, 360, 1); err == nil {
+		t.Error("all the egos go around together, but LoopSpeeds gave no error")
+	}
+	// But a single ego is never together with others
+	if _, err := LoopSpeeds(paths[:1], []float64{0.1}, 360, 1); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anthonynsimon/bild/adjust"
+	"github.com/anthonynsimon/bild/blend"
+	"github.com/anthonynsimon/bild/blur"
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/anthonynsimon/bild/perlin"
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// filter is a step of the chain, from one image to the next
+type filter func(img image.Image) image.Image
+
+// filterMaker makes a filter from its arguments on the command line,
+// and the seed for any noise it makes
+type filterMaker struct {
+	usage string
+	make  func(args []string, seed int64) (filter, error)
+}
+
+var filters = map[string]filterMaker{
+	"grain":      {"grain[:amount]", grain},
+	"vignette":   {"vignette[:strength,radius]", vignette},
+	"bloom":      {"bloom[:threshold,radius,strength]", bloom},
+	"aberration": {"aberration[:offset]", aberration},
+	"paper":      {"paper[:opacity,texture.png]", paper},
+	"lut":        {"lut:grade.cube[,strength]", lut},
+}
+
+// parseFilter makes a filter from its name and arguments,
+// like bloom:0.8,12 or lut:grade.cube
+func parseFilter(text string, seed int64) (filter, error) {
+	name, list, _ := strings.Cut(text, ":")
+	maker, ok := filters[name]
+	if !ok {
+		return nil, fmt.Errorf("no filter named %q", name)
+	}
+	args := []string{}
+	if list != "" {
+		args = strings.Split(list, ",")
+	}
+	f, err := maker.make(args, seed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w, use %s", name, err, maker.usage)
+	}
+	return f, nil
+}
+
+// floats parses the arguments, with the defaults for any left out
+func floats(args []string, defaults ...float64) ([]float64, error) {
+	if len(args) > len(defaults) {
+		return nil, fmt.Errorf("too many arguments")
+	}
+	values := append([]float64{}, defaults...)
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// gaussian is grey noise around mid grey, every pixel in turn,
+// so the same numbers always make the same noise
+func gaussian(width, height int, rng *rand.Rand) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		v := uint8(clamp(math.Round(rng.NormFloat64()*32+128), 0, 255))
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+	}
+	return img
+}
+
+// blotches is smooth grey perlin noise, in larger blotches the lower the frequency
+func blotches(width, height int, frequency float64, rng *rand.Rand) *image.RGBA {
+	p := perlin.NewPerlin(2, 2, 3, rng.Int63())
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((p.Noise2D(float64(x)/10*frequency, float64(y)/10*frequency) + 1) * 126)
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+// grain lays grey noise over the image, like the grain of film.
+// Mid grey leaves the image as it is, so it shows on dark and light alike.
+func grain(args []string, seed int64) (filter, error) {
+	v, err := floats(args, 0.15)
+	if err != nil {
+		return nil, err
+	}
+	return func(img image.Image) image.Image {
+		b := img.Bounds()
+		n := gaussian(b.Dx(), b.Dy(), rand.New(rand.NewSource(seed)))
+		return blend.Opacity(img, blend.LinearLight(img, n), v[0])
+	}, nil
+}
+
+// vignette darkens the image towards the corners, starting
+// the given fraction of the way out from the middle
+func vignette(args []string, _ int64) (filter, error) {
+	v, err := floats(args, 0.5, 0.4)
+	if err != nil {
+		return nil, err
+	}
+	strength, radius := v[0], v[1]
+	return func(img image.Image) image.Image {
+		b := img.Bounds()
+		cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+		corner := math.Hypot(float64(b.Dx())/2, float64(b.Dy())/2)
+
+		out := image.NewRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				t := clamp((math.Hypot(float64(x)-cx, float64(y)-cy)/corner-radius)/(1-radius), 0, 1)
+				shade := 1 - strength*t*t*(3-2*t)
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				out.SetRGBA(x, y, color.RGBA{scale(c.R, shade), scale(c.G, shade), scale(c.B, shade), c.A})
+			}
+		}
+		return out
+	}, nil
+}
+
+// bloom blurs the brightest parts of the image, and lays them back over it
+func bloom(args []string, _ int64) (filter, error) {
+	v, err := floats(args, 0.7, 12, 0.8)
+	if err != nil {
+		return nil, err
+	}
+	threshold, radius, strength := v[0], v[1], v[2]
+	return func(img image.Image) image.Image {
+		bright := adjust.Apply(img, func(c color.RGBA) color.RGBA {
+			if (0.2126*float64(c.R)+0.7152*float64(c.G)+0.0722*float64(c.B))/255 < threshold {
+				return color.RGBA{0, 0, 0, c.A}
+			}
+			return c
+		})
+		glow := blur.Gaussian(bright, radius)
+		return blend.Opacity(img, blend.Screen(img, glow), strength)
+	}, nil
+}
+
+// aberration splits red and blue apart, further and further out from
+// the middle, up to the given number of pixels in the corners
+func aberration(args []string, _ int64) (filter, error) {
+	v, err := floats(args, 4)
+	if err != nil {
+		return nil, err
+	}
+	offset := v[0]
+	return func(img image.Image) image.Image {
+		b := img.Bounds()
+		cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+		k := offset / math.Hypot(float64(b.Dx())/2, float64(b.Dy())/2)
+
+		at := func(x, y int, spread float64) color.RGBA {
+			sx := int(math.Round(cx + (float64(x)-cx)*spread))
+			sy := int(math.Round(cy + (float64(y)-cy)*spread))
+			sx = int(clamp(float64(sx), float64(b.Min.X), float64(b.Max.X-1)))
+			sy = int(clamp(float64(sy), float64(b.Min.Y), float64(b.Max.Y-1)))
+			return color.RGBAModel.Convert(img.At(sx, sy)).(color.RGBA)
+		}
+
+		out := image.NewRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := at(x, y, 1)
+				c.R = at(x, y, 1+k).R
+				c.B = at(x, y, 1-k).B
+				out.SetRGBA(x, y, c)
+			}
+		}
+		return out
+	}, nil
+}
+
+// paper lays a texture over the image, stretched over the whole of it,
+// or some blotchy noise like that of paper when there's none.
+// Like grain, mid grey in the texture leaves the image as it is.
+func paper(args []string, seed int64) (filter, error) {
+	path := ""
+	if len(args) > 1 {
+		path, args = args[1], args[:1]
+	}
+	v, err := floats(args, 0.4)
+	if err != nil {
+		return nil, err
+	}
+
+	var texture image.Image
+	if path != "" {
+		if texture, err = imgio.Open(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(img image.Image) image.Image {
+		b := img.Bounds()
+		t := texture
+		if t == nil {
+			rng := rand.New(rand.NewSource(seed))
+			fibres := blur.Box(gaussian(b.Dx(), b.Dy(), rng), 1)
+			// Faint, around mid grey
+			t = adjust.Contrast(blend.Opacity(fibres, blend.Overlay(fibres, blotches(b.Dx(), b.Dy(), 0.01, rng)), 0.5), -0.75)
+		}
+		t = transform.Resize(t, b.Dx(), b.Dy(), transform.Linear)
+		return blend.Opacity(img, blend.LinearLight(img, t), v[0])
+	}, nil
+}
+
+// lut grades the colours through a 3D lookup table from a .cube file,
+// as much as the given strength
+func lut(args []string, _ int64) (filter, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing the .cube file")
+	}
+	v, err := floats(args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	table, err := readCube(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(img image.Image) image.Image {
+		return adjust.Apply(img, func(c color.RGBA) color.RGBA {
+			in := [3]float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255}
+			graded := table.at(in)
+			for i := range graded {
+				graded[i] = in[i] + (graded[i]-in[i])*v[0]
+			}
+			return color.RGBA{unit(graded[0]), unit(graded[1]), unit(graded[2]), c.A}
+		})
+	}, nil
+}
+
+// cube is a 3D lookup table, with size entries along every channel
+// and red changing fastest
+type cube struct {
+	size   int
+	colors [][3]float64
+}
+
+func readCube(path string) (cube, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return cube{}, err
+	}
+	defer f.Close()
+
+	c := cube{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+		case fields[0] == "LUT_3D_SIZE" && len(fields) == 2:
+			if c.size, err = strconv.Atoi(fields[1]); err != nil {
+				return cube{}, err
+			}
+		case len(fields) == 3:
+			rgb, ok := [3]float64{}, true
+			for i, field := range fields {
+				v, err := strconv.ParseFloat(field, 64)
+				rgb[i], ok = v, ok && err == nil
+			}
+			// Keywords with a couple of values, like TITLE, aren't colours
+			if ok {
+				c.colors = append(c.colors, rgb)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cube{}, err
+	}
+
+	if c.size < 2 || len(c.colors) != c.size*c.size*c.size {
+		return cube{}, fmt.Errorf("%s: need a LUT_3D_SIZE and that many colours cubed, got %d", path, len(c.colors))
+	}
+	return c, nil
+}
+
+// at looks up a colour, blending between the nearest entries
+func (c cube) at(rgb [3]float64) [3]float64 {
+	last := float64(c.size - 1)
+	lo, hi, t := [3]int{}, [3]int{}, [3]float64{}
+	for i, v := range rgb {
+		p := clamp(v, 0, 1) * last
+		lo[i] = int(math.Floor(p))
+		hi[i] = int(math.Min(float64(lo[i]+1), last))
+		t[i] = p - float64(lo[i])
+	}
+
+	entry := func(r, g, b int) [3]float64 {
+		return c.colors[r+g*c.size+b*c.size*c.size]
+	}
+	out := [3]float64{}
+	for corner := 0; corner < 8; corner++ {
+		r, g, b, w := lo[0], lo[1], lo[2], 1.0
+		for i, pick := range []*int{&r, &g, &b} {
+			if corner&(1<<i) != 0 {
+				*pick, w = hi[i], w*t[i]
+			} else {
+				w *= 1 - t[i]
+			}
+		}
+		e := entry(r, g, b)
+		for i := range out {
+			out[i] += e[i] * w
+		}
+	}
+	return out
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// unit turns a channel from 0 to 1 into a byte
+func unit(v float64) uint8 {
+	return uint8(math.Round(clamp(v, 0, 1) * 255))
+}
+
+func scale(v uint8, by float64) uint8 {
+	return unit(float64(v) / 255 * by)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gradient is an image with every pixel a different colour
+func gradient() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), uint8(255 - x - y), 255})
+		}
+	}
+	return img
+}
+
+// writeCube saves a lookup table of the given size, with every entry
+// made from its red, green and blue fractions, to a file of its own
+func writeCube(t *testing.T, size int, entry func(r, g, b float64) [3]float64) string {
+	lines := []string{"TITLE \"test\"", fmt.Sprintf("LUT_3D_SIZE %d", size)}
+	last := float64(size - 1)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				c := entry(float64(r)/last, float64(g)/last, float64(b)/last)
+				lines = append(lines, fmt.Sprintf("%f %f %f", c[0], c[1], c[2]))
+			}
+		}
+	}
+	path := filepath.Join(t.TempDir(), "grade.cube")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pixels of the filtered image, whatever kind of image it comes out as
+func pixels(img image.Image) []byte {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(x, y, img.At(x, y))
+		}
+	}
+	return out.Pix
+}
+
+func TestNoiseFollowsTheSeed(t *testing.T) {
+	img := gradient()
+	for _, text := range []string{"grain", "grain:0.5", "paper", "paper:0.8"} {
+		run := func(seed int64) []byte {
+			f, err := parseFilter(text, seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pixels(f(img))
+		}
+
+		first, again := run(7), run(7)
+		if !bytes.Equal(first, again) {
+			t.Errorf("%s: two images from the same seed differ", text)
+		}
+		if bytes.Equal(first, run(8)) {
+			t.Errorf("%s: images from different seeds are the same", text)
+		}
+		if bytes.Equal(first, pixels(img)) {
+			t.Errorf("%s: left the image as it was", text)
+		}
+	}
+}
+
+func TestIdentityLUTLeavesPixelsAlone(t *testing.T) {
+	img := gradient()
+	for _, size := range []int{2, 5, 17} {
+		path := writeCube(t, size, func(r, g, b float64) [3]float64 { return [3]float64{r, g, b} })
+		f, err := parseFilter("lut:"+path, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pixels(f(img)), img.Pix) {
+			t.Errorf("identity table of size %d changed the image", size)
+		}
+	}
+}
+
+func TestLUTBlendsBetweenEntries(t *testing.T) {
+	// Inverting every channel is linear, so it's the same between entries
+	path := writeCube(t, 2, func(r, g, b float64) [3]float64 { return [3]float64{1 - r, 1 - g, 1 - b} })
+	c, err := readCube(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.at([3]float64{0.25, 0.5, 0.9}); !near(got[0], 0.75) || !near(got[1], 0.5) || !near(got[2], 0.1) {
+		t.Errorf("inverted 0.25, 0.5, 0.9 = %v", got)
+	}
+
+	// At half strength, halfway to the inverted colours
+	f, err := parseFilter("lut:"+path+",0.5", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 51, 255})
+	if got := f(img).At(0, 0); got != (color.RGBA{128, 128, 128, 255}) {
+		t.Errorf("half inverted %v, want mid grey", got)
+	}
+}
+
+func TestReadCubeRejectsTheWrongNumberOfEntries(t *testing.T) {
+	path := writeCube(t, 3, func(r, g, b float64) [3]float64 { return [3]float64{r, g, b} })
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, text := range map[string]string{
+		"size too big":   strings.Replace(string(data), "LUT_3D_SIZE 3", "LUT_3D_SIZE 4", 1),
+		"size too small": strings.Replace(string(data), "LUT_3D_SIZE 3", "LUT_3D_SIZE 2", 1),
+		"no size":        strings.Replace(string(data), "LUT_3D_SIZE 3", "", 1),
+		"entry missing":  string(data[:strings.LastIndex(string(data), "\n")]),
+	} {
+		bad := filepath.Join(t.TempDir(), "bad.cube")
+		if err := os.WriteFile(bad, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readCube(bad); err == nil {
+			t.Errorf("%s: readCube gave no error", name)
+		}
+	}
+}
+
+func TestVignette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 101, 81))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	f, err := parseFilter("vignette:0.5,0.4", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := f(img)
+
+	if got := out.At(50, 40); got != (color.RGBA{200, 200, 200, 200}) {
+		t.Errorf("middle %v, want it as it was", got)
+	}
+	// All the strength in the corners, and some of it on the way there
+	for _, p := range []image.Point{{0, 0}, {100, 0}, {0, 80}, {100, 80}} {
+		if r, _, _, _ := out.At(p.X, p.Y).RGBA(); r>>8 > 102 {
+			t.Errorf("corner %v is %d, want darkened to about half", p, r>>8)
+		}
+	}
+	if r, _, _, _ := out.At(90, 70).RGBA(); r>>8 >= 200 || r>>8 <= 100 {
+		t.Errorf("near the corner is %d, want between the middle and the corner", r>>8)
+	}
+}
+
+func near(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
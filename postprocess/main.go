@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/anthonynsimon/bild/imgio"
+)
+
+// postprocess runs the final image of any sketch through a chain of filters,
+// in the order they're given, like:
+//
+//	postprocess -in output.png -out final.png bloom:0.6,20 vignette grain:0.1
+func main() {
+	in := flag.String("in", "output.png", "image to filter")
+	out := flag.String("out", "final.png", "where to save the filtered image")
+	seed := flag.Int64("seed", 1337, "seed for the noise of grain and paper")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] filter...\n\nFilters:\n", os.Args[0])
+		names := []string{}
+		for name := range filters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", filters[name].usage)
+		}
+		fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Every filter is checked before any of them runs
+	chain := []filter{}
+	for _, arg := range flag.Args() {
+		f, err := parseFilter(arg, *seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		chain = append(chain, f)
+	}
+
+	img, err := imgio.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range chain {
+		img = f(img)
+	}
+	if err := imgio.Save(*out, img, imgio.PNGEncoder()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package report sets up logging the same way for every sketch,
+// and logs how far along the animations are.
+package report
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Log sends everything at the level or above to stderr
+func Log(level slog.Level) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+// Progress logs how many frames of an animation are done,
+// and about how long the rest will take, every few seconds
+type Progress struct {
+	total, done int
+	start, last time.Time
+	every       time.Duration
+}
+
+func NewProgress(total int) *Progress {
+	now := time.Now()
+	return &Progress{total: total, start: now, last: now, every: 2 * time.Second}
+}
+
+// Frame counts another frame done
+func (p *Progress) Frame() {
+	p.done++
+	now := time.Now()
+	if now.Sub(p.last) < p.every && p.done < p.total {
+		return
+	}
+	p.last = now
+
+	elapsed := now.Sub(p.start)
+	left := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+	slog.Info("rendering", "frame", p.done, "of", p.total, "elapsed", elapsed.Round(time.Second), "eta", left.Round(time.Second))
+}
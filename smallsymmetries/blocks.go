@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// generator fills a block of the composition, s wide,
+// with its top left corner at x, y
+type generator func(dc *gg.Context, x, y, s float64)
+
+// blockStyle is how blocks are drawn
+type blockStyle struct {
+	thin, thick float64 // Widths of the lines
+	padding     float64 // Between the edge of a block and what's drawn in it
+	theme       string  // Palette every tile picks its colour from, none for the current colour
+}
+
+// generators returns every block generator by name
+func generators(w blockStyle) map[string]generator {
+	return map[string]generator{
+		"random":      randomLines(w),
+		"symmetrical": symmetricalLines(w),
+		"empty":       func(dc *gg.Context, x, y, s float64) {},
+		"truchet":     truchetTriangles(w),
+		"diagonals":   truchetDiagonals(w),
+		"smith":       smithArcs(w),
+		"multiscale":  multiscaleArcs(w, 3),
+		"star":        starTile(w),
+		"wallpaper":   wallpaperBlock(w),
+		"rosette":     rosetteBlock(w),
+	}
+}
+
+// randomLines draws a few thin lines, anywhere in the block
+func randomLines(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		for i := 0; i < 2+int(rand.Int31n(6)); i++ {
+			x0 := x + rand.Float64()*s
+			x1 := x + rand.Float64()*s
+			y0 := y + rand.Float64()*s
+			y1 := y + rand.Float64()*s
+			dc.DrawLine(x0, y0, x1, y1)
+			dc.SetLineWidth(w.thin)
+			dc.Stroke()
+		}
+	}
+}
+
+// symmetricalLines draws thick diameters, evenly spread around the block
+func symmetricalLines(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		steps := (2 + rand.Int31n(5))
+		angleStep := float64(360 / steps)
+		angle := 0.0
+
+		for i := int32(0); i < steps; i++ {
+			sin, cos := math.Sincos(gg.Radians(angle))
+			x0 := x + s/2 + s/2*cos
+			y0 := y + s/2 + s/2*sin
+			sin, cos = math.Sincos(gg.Radians(angle + 180))
+			x1 := x + s/2 + s/2*cos
+			y1 := y + s/2 + s/2*sin
+			dc.DrawLine(x0, y0, x1, y1)
+			dc.SetLineWidth(w.thick)
+			dc.Stroke()
+
+			angle += angleStep
+		}
+	}
+}
+
+// placement picks the generator of the block at x, y by name,
+// out of a grid of blocks on either side
+type placement func(x, y, blocks int) string
+
+// tiled repeats a pattern across the grid, with rows split by slashes
+// and a character for every block, standing for a generator by keys
+func tiled(pattern string, keys map[rune]string) (placement, error) {
+	rows := [][]rune{}
+	for n, row := range strings.Split(pattern, "/") {
+		if row == "" {
+			return nil, fmt.Errorf("row %d of pattern %q has no blocks", n+1, pattern)
+		}
+		rows = append(rows, []rune(row))
+	}
+
+	return func(x, y, blocks int) string {
+		row := rows[y%len(rows)]
+		key := row[x%len(row)]
+		if name, ok := keys[key]; ok {
+			return name
+		}
+		return string(key)
+	}, nil
+}
+
+// weighted picks any of the generators, as often as their chances say
+func weighted(chances map[string]float64) placement {
+	// Sorted, for the same composition from the same seed
+	names := []string{}
+	total := 0.0
+	for name, chance := range chances {
+		names = append(names, name)
+		total += chance
+	}
+	sort.Strings(names)
+
+	return func(x, y, blocks int) string {
+		pick := rand.Float64() * total
+		for _, name := range names {
+			pick -= chances[name]
+			if pick < 0 {
+				return name
+			}
+		}
+		return names[len(names)-1]
+	}
+}
+
+// scattered picks any of the generators, all of them as often
+func scattered(names ...string) placement {
+	chances := map[string]float64{}
+	for _, name := range names {
+		chances[name] = 1
+	}
+	return weighted(chances)
+}
+
+// checkerboard alternates between two generators
+func checkerboard(a, b string) placement {
+	return func(x, y, blocks int) string {
+		if (x+y)%2 == 0 {
+			return a
+		}
+		return b
+	}
+}
+
+// diagonal uses the first generator along both diagonals,
+// and the second one everywhere else
+func diagonal(a, b string) placement {
+	return func(x, y, blocks int) string {
+		if x == y || x+y == blocks-1 {
+			return a
+		}
+		return b
+	}
+}
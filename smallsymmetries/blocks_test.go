@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestTiled(t *testing.T) {
+	place, err := tiled("rs/xr", map[rune]string{'r': "random", 's': "symmetrical"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Repeating across the grid, with unknown keys as names of their own
+	for _, c := range []struct {
+		x, y int
+		want string
+	}{
+		{0, 0, "random"}, {1, 0, "symmetrical"}, {2, 0, "random"},
+		{0, 1, "x"}, {1, 1, "random"}, {3, 3, "random"}, {2, 3, "x"},
+	} {
+		if got := place(c.x, c.y, 8); got != c.want {
+			t.Errorf("block %d, %d is %q, want %q", c.x, c.y, got, c.want)
+		}
+	}
+
+	for _, pattern := range []string{"", "rr//rs", "rr/", "/rr"} {
+		if _, err := tiled(pattern, nil); err == nil {
+			t.Errorf("tiled(%q) gave no error", pattern)
+		}
+	}
+}
+
+func TestThemesHaveColours(t *testing.T) {
+	for _, name := range themeNames() {
+		if len(palletes[name]) == 0 {
+			t.Errorf("theme %q has no colours", name)
+		}
+	}
+}
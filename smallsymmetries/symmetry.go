@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
+// affine maps x, y to a*x + b*y + tx, c*x + d*y + ty
+type affine struct {
+	a, b, c, d, tx, ty float64
+}
+
+var identity = affine{a: 1, d: 1}
+
+func (m affine) point(x, y float64) (float64, float64) {
+	return m.a*x + m.b*y + m.tx, m.c*x + m.d*y + m.ty
+}
+
+// then returns the map that applies m first, and n after it
+func (m affine) then(n affine) affine {
+	return affine{
+		a:  n.a*m.a + n.b*m.c,
+		b:  n.a*m.b + n.b*m.d,
+		c:  n.c*m.a + n.d*m.c,
+		d:  n.c*m.b + n.d*m.d,
+		tx: n.a*m.tx + n.b*m.ty + n.tx,
+		ty: n.c*m.tx + n.d*m.ty + n.ty,
+	}
+}
+
+func (m affine) inverse() affine {
+	det := m.a*m.d - m.b*m.c
+	inv := affine{a: m.d / det, b: -m.b / det, c: -m.c / det, d: m.a / det}
+	inv.tx, inv.ty = inv.point(-m.tx, -m.ty)
+	return inv
+}
+
+// pen draws on the canvas through a map, so that a motif
+// can be drawn again and again, moved, turned and mirrored
+type pen struct {
+	dc *gg.Context
+	m  affine
+}
+
+func (p pen) MoveTo(x, y float64) {
+	p.dc.MoveTo(p.m.point(x, y))
+}
+
+func (p pen) LineTo(x, y float64) {
+	p.dc.LineTo(p.m.point(x, y))
+}
+
+func (p pen) DrawLine(x1, y1, x2, y2 float64) {
+	p.MoveTo(x1, y1)
+	p.LineTo(x2, y2)
+}
+
+func (p pen) DrawCircle(x, y, r float64) {
+	// Maps are only ever moves, turns, mirrors and scales,
+	// so circles stay circles
+	cx, cy := p.m.point(x, y)
+	p.dc.NewSubPath()
+	p.dc.DrawCircle(cx, cy, r*math.Sqrt(math.Abs(p.m.a*p.m.d-p.m.b*p.m.c)))
+}
+
+// motif is what gets repeated, drawn in units of the size of a cell
+type motif func(p pen)
+
+// group is a symmetry group: the ways a motif is copied around a cell.
+// Wallpaper groups also repeat the cell along a lattice.
+type group struct {
+	lattice [2][2]float64 // The sides of the cell, none for rosettes
+	ops     []affine      // In fractions of the sides of the cell, for wallpaper groups
+}
+
+// op returns an operation on fractions of the sides of a cell,
+// as in the International Tables for Crystallography
+func op(a, b, c, d, tx, ty float64) affine {
+	return affine{a: a, b: b, c: c, d: d, tx: tx, ty: ty}
+}
+
+// with returns the operations followed by more of them,
+// without touching the ones it started from
+func with(ops []affine, more ...affine) []affine {
+	return append(append([]affine{}, ops...), more...)
+}
+
+// centred adds the same operations, shifted to the middle of the cell
+func centred(ops []affine) []affine {
+	shifted := []affine{}
+	for _, o := range ops {
+		o.tx += 0.5
+		o.ty += 0.5
+		shifted = append(shifted, o)
+	}
+	return with(ops, shifted...)
+}
+
+var (
+	oblique     = [2][2]float64{{1, 0}, {0.3, 0.85}}
+	rectangular = [2][2]float64{{1, 0}, {0, 0.75}}
+	square      = [2][2]float64{{1, 0}, {0, 1}}
+	hexagonal   = [2][2]float64{{1, 0}, {-0.5, math.Sqrt(3) / 2}}
+
+	p2Ops  = []affine{identity, op(-1, 0, 0, -1, 0, 0)}
+	pmmOps = with(p2Ops, op(-1, 0, 0, 1, 0, 0), op(1, 0, 0, -1, 0, 0))
+	p4Ops  = with(p2Ops, op(0, -1, 1, 0, 0, 0), op(0, 1, -1, 0, 0, 0))
+	p3Ops  = []affine{identity, op(0, -1, 1, -1, 0, 0), op(-1, 1, -1, 0, 0, 0)}
+	p6Ops  = with(p3Ops, op(-1, 0, 0, -1, 0, 0), op(0, 1, -1, 1, 0, 0), op(1, -1, 1, 0, 0, 0))
+	// The mirrors of p3m1 and p31m, which p6m has both of
+	p3m1Mirrors = []affine{op(0, -1, -1, 0, 0, 0), op(-1, 1, 0, 1, 0, 0), op(1, 0, 1, -1, 0, 0)}
+	p31mMirrors = []affine{op(0, 1, 1, 0, 0, 0), op(1, -1, 0, -1, 0, 0), op(-1, 0, -1, 1, 0, 0)}
+)
+
+// wallpapers holds the 17 wallpaper groups, by their short names
+var wallpapers = map[string]group{
+	"p1":   {oblique, []affine{identity}},
+	"p2":   {oblique, p2Ops},
+	"pm":   {rectangular, []affine{identity, op(-1, 0, 0, 1, 0, 0)}},
+	"pg":   {rectangular, []affine{identity, op(-1, 0, 0, 1, 0, 0.5)}},
+	"cm":   {rectangular, centred([]affine{identity, op(-1, 0, 0, 1, 0, 0)})},
+	"pmm":  {rectangular, pmmOps},
+	"pmg":  {rectangular, with(p2Ops, op(-1, 0, 0, 1, 0.5, 0), op(1, 0, 0, -1, 0.5, 0))},
+	"pgg":  {rectangular, with(p2Ops, op(-1, 0, 0, 1, 0.5, 0.5), op(1, 0, 0, -1, 0.5, 0.5))},
+	"cmm":  {rectangular, centred(pmmOps)},
+	"p4":   {square, p4Ops},
+	"p4m":  {square, with(p4Ops, op(-1, 0, 0, 1, 0, 0), op(1, 0, 0, -1, 0, 0), op(0, 1, 1, 0, 0, 0), op(0, -1, -1, 0, 0, 0))},
+	"p4g":  {square, with(p4Ops, op(-1, 0, 0, 1, 0.5, 0.5), op(1, 0, 0, -1, 0.5, 0.5), op(0, 1, 1, 0, 0.5, 0.5), op(0, -1, -1, 0, 0.5, 0.5))},
+	"p3":   {hexagonal, p3Ops},
+	"p3m1": {hexagonal, with(p3Ops, p3m1Mirrors...)},
+	"p31m": {hexagonal, with(p3Ops, p31mMirrors...)},
+	"p6":   {hexagonal, p6Ops},
+	"p6m":  {hexagonal, with(with(p6Ops, p3m1Mirrors...), p31mMirrors...)},
+}
+
+// wallpaper returns the wallpaper group with the given name
+func wallpaper(name string) (group, error) {
+	g, ok := wallpapers[name]
+	if !ok {
+		return group{}, fmt.Errorf("no wallpaper group named %q", name)
+	}
+	return g, nil
+}
+
+// wallpaperNames returns the names of the wallpaper groups in a stable order
+func wallpaperNames() []string {
+	names := []string{}
+	for name := range wallpapers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rosette returns the group of n turns around the center,
+// with as many mirrors when dihedral
+func rosette(n int, dihedral bool) group {
+	g := group{}
+	for k := 0; k < n; k++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(k) / float64(n))
+		turn := affine{a: cos, b: -sin, c: sin, d: cos}
+		g.ops = append(g.ops, turn)
+		if dihedral {
+			g.ops = append(g.ops, affine{a: 1, d: -1}.then(turn))
+		}
+	}
+	return g
+}
+
+// basis maps fractions of the sides of a cell to the canvas, in cells
+func (g group) basis() affine {
+	return affine{a: g.lattice[0][0], b: g.lattice[1][0], c: g.lattice[0][1], d: g.lattice[1][1]}
+}
+
+// fill draws the motif over the rectangle at x, y, repeated under the
+// symmetries of the group, with cells of the given size. Rosettes go
+// in the middle of the rectangle, with the motif size away from it at most.
+func (g group) fill(dc *gg.Context, m motif, x, y, w, h, size float64) {
+	dc.Push()
+	defer dc.Pop()
+	// Popping keeps the clip, so it has to go by hand
+	defer dc.ResetClip()
+	dc.DrawRectangle(x, y, w, h)
+	dc.Clip()
+
+	if g.lattice == [2][2]float64{} {
+		place := affine{a: size, d: size, tx: x + w/2, ty: y + h/2}
+		for _, o := range g.ops {
+			m(pen{dc, o.then(place)})
+		}
+		return
+	}
+
+	// Operations work on fractions of the sides of the cell,
+	// so the motif goes there and back again around them
+	basis := g.basis()
+	ops := []affine{}
+	for _, o := range g.ops {
+		ops = append(ops, basis.inverse().then(o).then(basis))
+	}
+
+	// Every cell that overlaps the rectangle, and the ones around them
+	// for the copies of the motif that reach outside their own cell
+	toCell := basis.then(affine{a: size, d: size, tx: x, ty: y}).inverse()
+	minI, minJ, maxI, maxJ := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{x, y}, {x + w, y}, {x, y + h}, {x + w, y + h}} {
+		i, j := toCell.point(corner[0], corner[1])
+		minI, maxI = math.Min(minI, i), math.Max(maxI, i)
+		minJ, maxJ = math.Min(minJ, j), math.Max(maxJ, j)
+	}
+
+	for i := math.Floor(minI) - 2; i <= maxI+2; i++ {
+		for j := math.Floor(minJ) - 2; j <= maxJ+2; j++ {
+			cx, cy := basis.point(i, j)
+			place := affine{a: size, d: size, tx: x + cx*size, ty: y + cy*size}
+			for _, o := range ops {
+				m(pen{dc, o.then(place)})
+			}
+		}
+	}
+}
+
+// randomMotif picks a few lines and circles at random within a cell,
+// and draws the same ones every time
+func randomMotif(lines int, width float64) motif {
+	segments := make([][4]float64, lines)
+	for i := range segments {
+		segments[i] = [4]float64{rand.Float64(), rand.Float64(), rand.Float64(), rand.Float64()}
+	}
+	dot := [3]float64{rand.Float64(), rand.Float64(), 0.02 + rand.Float64()*0.08}
+
+	return func(p pen) {
+		for _, s := range segments {
+			p.DrawLine(s[0], s[1], s[2], s[3])
+		}
+		p.dc.SetLineWidth(width)
+		p.dc.Stroke()
+
+		p.DrawCircle(dot[0], dot[1], dot[2])
+		p.dc.Fill()
+	}
+}
+
+// wallpaperBlock fills the block with a random motif,
+// repeated under any of the wallpaper groups
+func wallpaperBlock(w blockStyle) generator {
+	names := wallpaperNames()
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		g := wallpapers[names[rand.Intn(len(names))]]
+		g.fill(dc, randomMotif(2, w.thin), x, y, s, s, s/3)
+	}
+}
+
+// rosetteBlock draws a random motif turned around the middle of the block
+// a few times, mirrored or not
+func rosetteBlock(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		// Motifs reach out to the far corner of their cell
+		g := rosette(3+rand.Intn(6), rand.Intn(2) == 0)
+		g.fill(dc, randomMotif(2, w.thick), x, y, s, s, s/2/math.Sqrt2)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// sameOp is whether two operations are the same, but for moving
+// a whole number of cells along, when they're on fractions of a cell
+func sameOp(m, n affine, modulo bool) bool {
+	dx, dy := m.tx-n.tx, m.ty-n.ty
+	if modulo {
+		dx, dy = dx-math.Round(dx), dy-math.Round(dy)
+	}
+	return near(m.a, n.a) && near(m.b, n.b) && near(m.c, n.c) && near(m.d, n.d) && near(dx, 0) && near(dy, 0)
+}
+
+func contains(ops []affine, o affine, modulo bool) bool {
+	for _, p := range ops {
+		if sameOp(p, o, modulo) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGroup checks the operations are a group: the identity is there,
+// and any two of them one after the other make another one
+func checkGroup(t *testing.T, name string, ops []affine, modulo bool) {
+	t.Helper()
+	if !contains(ops, identity, modulo) {
+		t.Errorf("%s has no identity", name)
+	}
+	for i, m := range ops {
+		for j, n := range ops {
+			if !contains(ops, m.then(n), modulo) {
+				t.Errorf("%s: operation %d then %d = %+v, which isn't in the group", name, i, j, m.then(n))
+			}
+			if j > i && sameOp(m, n, modulo) {
+				t.Errorf("%s: operations %d and %d are the same", name, i, j)
+			}
+		}
+	}
+}
+
+func TestWallpaperGroups(t *testing.T) {
+	order := map[string]int{
+		"p1": 1, "p2": 2, "pm": 2, "pg": 2, "cm": 4, "pmm": 4, "pmg": 4, "pgg": 4, "cmm": 8,
+		"p4": 4, "p4m": 8, "p4g": 8, "p3": 3, "p3m1": 6, "p31m": 6, "p6": 6, "p6m": 12,
+	}
+	if len(wallpapers) != 17 {
+		t.Errorf("%d wallpaper groups, want 17", len(wallpapers))
+	}
+
+	for _, name := range wallpaperNames() {
+		g := wallpapers[name]
+		if len(g.ops) != order[name] {
+			t.Errorf("%s has %d operations, want %d", name, len(g.ops), order[name])
+		}
+		checkGroup(t, name, g.ops, true)
+
+		// On the canvas, every operation keeps lengths and angles as they are
+		basis := g.basis()
+		for i, o := range g.ops {
+			m := basis.inverse().then(o).then(basis)
+			if !near(m.a*m.a+m.c*m.c, 1) || !near(m.b*m.b+m.d*m.d, 1) || !near(m.a*m.b+m.c*m.d, 0) {
+				t.Errorf("%s: operation %d stretches the motif, as %+v", name, i, m)
+			}
+		}
+	}
+
+	if _, err := wallpaper("p5"); err == nil {
+		t.Error("there's no p5, but wallpaper gave no error")
+	}
+}
+
+func TestRosettes(t *testing.T) {
+	for n := 1; n <= 12; n++ {
+		for _, dihedral := range []bool{false, true} {
+			g := rosette(n, dihedral)
+			want := n
+			if dihedral {
+				want = 2 * n
+			}
+			if len(g.ops) != want {
+				t.Errorf("rosette(%d, %v) has %d operations, want %d", n, dihedral, len(g.ops), want)
+			}
+			checkGroup(t, "rosette", g.ops, false)
+		}
+	}
+}
+
+func TestAffine(t *testing.T) {
+	maps := []affine{
+		identity,
+		op(0, -1, 1, 0, 0.5, 0.25),
+		{a: 2, b: 0.5, c: -1, d: 3, tx: 10, ty: -4},
+		{a: -1, d: 1, tx: 3},
+	}
+	for _, m := range maps {
+		for _, n := range maps {
+			// One after the other, like applying both in turn
+			x, y := n.point(m.point(0.3, -1.7))
+			if gx, gy := m.then(n).point(0.3, -1.7); !near(gx, x) || !near(gy, y) {
+				t.Errorf("%+v then %+v takes the point to %v, %v, want %v, %v", m, n, gx, gy, x, y)
+			}
+		}
+		if !sameOp(m.then(m.inverse()), identity, false) || !sameOp(m.inverse().then(m), identity, false) {
+			t.Errorf("%+v and its inverse don't undo each other", m)
+		}
+		if !sameOp(m.inverse().inverse(), m, false) {
+			t.Errorf("the inverse of the inverse of %+v is %+v", m, m.inverse().inverse())
+		}
+	}
+}
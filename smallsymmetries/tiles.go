@@ -0,0 +1,181 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/fogleman/gg"
+)
+
+// Tiles meet their neighbours at the middle of their edges, or at their
+// corners, always in the same places. Across the padding between blocks
+// they reach out with bridges, so lines run on from one tile to the next.
+
+// pickColor sets the colour of a tile, from the theme when there's one
+func (w blockStyle) pickColor(dc *gg.Context) {
+	if w.theme == "" {
+		return
+	}
+	r, g, b := getColor(w.theme)
+	dc.SetRGB255(int(r), int(g), int(b))
+}
+
+// bridges reach out from the edges of a block, across the padding,
+// to meet the bridges of the blocks around it
+func (w blockStyle) bridges(dc *gg.Context, x, y, s, width float64, midpoints, corners bool) {
+	p := w.padding
+	if midpoints {
+		dc.DrawLine(x+s/2, y, x+s/2, y-p)
+		dc.DrawLine(x+s/2, y+s, x+s/2, y+s+p)
+		dc.DrawLine(x, y+s/2, x-p, y+s/2)
+		dc.DrawLine(x+s, y+s/2, x+s+p, y+s/2)
+	}
+	if corners {
+		dc.DrawLine(x, y, x-p, y-p)
+		dc.DrawLine(x+s, y, x+s+p, y-p)
+		dc.DrawLine(x, y+s, x-p, y+s+p)
+		dc.DrawLine(x+s, y+s, x+s+p, y+s+p)
+	}
+	dc.SetLineWidth(width)
+	dc.Stroke()
+}
+
+// truchetTriangles fills half of the block, on either side of a diagonal,
+// like Truchet's original tiles
+func truchetTriangles(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		corners := [][2]float64{{x, y}, {x + s, y}, {x + s, y + s}, {x, y + s}}
+		// Leave out one of the corners
+		skip := rand.Intn(len(corners))
+		for i := 1; i < len(corners); i++ {
+			corner := corners[(skip+i)%len(corners)]
+			dc.LineTo(corner[0], corner[1])
+		}
+		dc.ClosePath()
+		dc.Fill()
+	}
+}
+
+// truchetDiagonals draws one of the two diagonals of the block
+func truchetDiagonals(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		if rand.Intn(2) == 0 {
+			dc.DrawLine(x, y, x+s, y+s)
+		} else {
+			dc.DrawLine(x+s, y, x, y+s)
+		}
+		dc.SetLineWidth(w.thick)
+		dc.Stroke()
+		w.bridges(dc, x, y, s, w.thick, false, true)
+	}
+}
+
+// traceSmith traces the two quarter circles of a Smith tile,
+// joining up the middles of its edges around either pair of opposite corners
+func traceSmith(dc *gg.Context, x, y, s float64, flipped bool) {
+	r := s / 2
+	dc.NewSubPath()
+	if flipped {
+		dc.DrawArc(x+s, y, r, math.Pi/2, math.Pi)
+		dc.NewSubPath()
+		dc.DrawArc(x, y+s, r, math.Pi*3/2, math.Pi*2)
+		return
+	}
+	dc.DrawArc(x, y, r, 0, math.Pi/2)
+	dc.NewSubPath()
+	dc.DrawArc(x+s, y+s, r, math.Pi, math.Pi*3/2)
+}
+
+// smithArcs draws the quarter circles of a Smith tile
+func smithArcs(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		traceSmith(dc, x, y, s, rand.Intn(2) == 0)
+		dc.SetLineWidth(w.thick)
+		dc.Stroke()
+		w.bridges(dc, x, y, s, w.thick, true, false)
+	}
+}
+
+// multiscaleArcs draws a Smith tile, and fills the two quarters its
+// arcs don't go through with smaller tiles of their own, and so on down
+// to the given depth, with thinner lines at every level. Only the largest
+// tile joins up with the blocks around it.
+func multiscaleArcs(w blockStyle, depth int) generator {
+	var tile func(dc *gg.Context, x, y, s, width float64, level int)
+	tile = func(dc *gg.Context, x, y, s, width float64, level int) {
+		flipped := rand.Intn(2) == 0
+		traceSmith(dc, x, y, s, flipped)
+		dc.SetLineWidth(width)
+		dc.Stroke()
+
+		if level == depth {
+			return
+		}
+		free := [][2]float64{{1, 0}, {0, 1}}
+		if flipped {
+			free = [][2]float64{{0, 0}, {1, 1}}
+		}
+		for _, q := range free {
+			if rand.Float64() < 0.75 {
+				tile(dc, x+q[0]*s/2, y+q[1]*s/2, s/2, math.Max(w.thin, width*2/3), level+1)
+			}
+		}
+	}
+
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		tile(dc, x, y, s, w.thick, 1)
+		w.bridges(dc, x, y, s, w.thick, true, false)
+	}
+}
+
+// starTile draws an eight pointed star, with its points reaching
+// the middles of the edges, and lines on to the corners,
+// so that stars across the blocks join up in a lattice
+func starTile(w blockStyle) generator {
+	return func(dc *gg.Context, x, y, s float64) {
+		dc.Push()
+		defer dc.Pop()
+		w.pickColor(dc)
+
+		cx, cy, r := x+s/2, y+s/2, s/2
+		point := func(k int) (float64, float64) {
+			sin, cos := math.Sincos(float64(k) * math.Pi / 4)
+			return cx + r*cos, cy + r*sin
+		}
+
+		// Every point joins the one three points along
+		for k := 0; k < 8; k++ {
+			px, py := point(k * 3)
+			dc.LineTo(px, py)
+		}
+		dc.ClosePath()
+
+		// The diagonal points go on to the corners
+		for k := 1; k < 8; k += 2 {
+			px, py := point(k)
+			sin, cos := math.Sincos(float64(k) * math.Pi / 4)
+			dc.MoveTo(px, py)
+			dc.LineTo(cx+s/2*math.Copysign(1, cos), cy+s/2*math.Copysign(1, sin))
+		}
+		dc.SetLineWidth(w.thick)
+		dc.Stroke()
+
+		w.bridges(dc, x, y, s, w.thick, true, true)
+	}
+}
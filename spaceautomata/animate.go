@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/dangelov/martegeno/report"
+	"github.com/fogleman/gg"
+)
+
+// frameSink takes the frames of an animation, one after another
+type frameSink interface {
+	add(img image.Image) error
+}
+
+// pngSequence saves every frame to a numbered PNG file
+type pngSequence struct {
+	pattern string // Name of the files, with a %d for the frame number
+	count   int
+}
+
+func (p *pngSequence) add(img image.Image) error {
+	err := gg.SavePNG(fmt.Sprintf(p.pattern, p.count), img)
+	p.count++
+	return err
+}
+
+// reporting passes frames on to another sink, logging the progress
+type reporting struct {
+	frameSink
+	progress *report.Progress
+}
+
+func (r reporting) add(img image.Image) error {
+	if err := r.frameSink.add(img); err != nil {
+		return err
+	}
+	r.progress.Frame()
+	return nil
+}
+
+// setRule changes the rule the automaton follows from now on
+func (a *automata) setRule(r rule) {
+	a.rule = r
+	a.fast = nil
+}
+
+// morphRule returns the elementary rule the given fraction of the way
+// from one rule to another, switching the neighbourhoods where they
+// differ one at a time, so every step along the way is a rule of its own
+func morphRule(from, to uint8, t float64) uint8 {
+	differ := []uint{}
+	for n := uint(0); n < 8; n++ {
+		if (from^to)&(1<<n) != 0 {
+			differ = append(differ, n)
+		}
+	}
+
+	switched := int(math.Round(t * float64(len(differ))))
+	number := from
+	for _, n := range differ[:switched] {
+		number ^= 1 << n
+	}
+	return number
+}
+
+// scroll animates the automaton, scrolling the generations up
+// as new ones come in at the bottom, a few frames per generation.
+// When morphing, the rule goes from one number to the other along the way.
+type scroll struct {
+	size        float64 // Of the canvas
+	cell        float64 // Height of every generation
+	generations int     // New generations over the whole animation
+	steps       int     // Frames per generation
+	morph       bool
+	from, to    uint8
+	style       renderStyle
+	background  color.Color
+	clip        func(dc *gg.Context) // Shape of the visible area, if any
+}
+
+func (sc scroll) animate(auto *automata, sink frameSink) error {
+	if sc.morph {
+		auto.setRule(wolframRule(sc.from))
+	}
+
+	// The screen starts out full, with one more generation
+	// just below the bottom, ready to scroll in
+	rows := int(math.Ceil(sc.size/sc.cell)) + 1
+	history := [][]int{}
+	for len(history) < rows {
+		history = append(history, append([]int{}, auto.cells...))
+		auto.advance()
+	}
+
+	for g := 0; g < sc.generations; g++ {
+		if sc.morph {
+			t := float64(g) / math.Max(1, float64(sc.generations-1))
+			auto.setRule(wolframRule(morphRule(sc.from, sc.to, t)))
+		}
+
+		for step := 0; step < sc.steps; step++ {
+			dc := gg.NewContext(int(sc.size), int(sc.size))
+			dc.SetColor(sc.background)
+			dc.Clear()
+			if sc.clip != nil {
+				sc.clip(dc)
+			}
+
+			dc.Translate(0, -sc.cell*float64(step)/float64(sc.steps))
+			for row, cells := range history {
+				var above []int
+				if row > 0 {
+					above = history[row-1]
+				}
+				shown := &automata{cells: cells, boundary: auto.boundary, colors: auto.colors}
+				shown.drawGeneration(dc, rowsLayout{sc.cell}, sc.style, row, above)
+			}
+
+			if err := sink.add(dc.Image()); err != nil {
+				return err
+			}
+		}
+
+		// The top generation scrolls out, and the next one comes in
+		history = append(history[1:], append([]int{}, auto.cells...))
+		auto.advance()
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+)
+
+// boundary is what an automaton finds past its edges
+type boundary int
+
+const (
+	wrap      boundary = iota // The other edge
+	fixedDead                 // Empty cells
+	fixedLive                 // Live cells
+	reflect                   // The cells before the edge, mirrored
+)
+
+var boundaries = map[string]boundary{
+	"wrap":    wrap,
+	"fixed-0": fixedDead,
+	"fixed-1": fixedLive,
+	"reflect": reflect,
+}
+
+// at returns the state of the cell at i, even past the edges
+func (a *automata) at(i int) int {
+	n := len(a.cells)
+	if i >= 0 && i < n {
+		return a.cells[i]
+	}
+
+	switch a.boundary {
+	case fixedDead:
+		return 0
+	case fixedLive:
+		return 1
+	case reflect:
+		// Bounce back and forth between the edges,
+		// with the edge cells showing up twice
+		i = (i%(2*n) + 2*n) % (2 * n)
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return a.cells[i]
+	default:
+		return a.cells[(i%n+n)%n]
+	}
+}
+
+// initRandom brings cells to life with the given density,
+// each in any state but the empty one
+func (a *automata) initRandom(num int, density float64) {
+	a.cells = make([]int, num)
+	for i := 0; i < num; i++ {
+		if rand.Float64() < density {
+			a.cells[i] = 1
+			if a.rule.states > 2 {
+				a.cells[i] += rand.Intn(a.rule.states - 1)
+			}
+		}
+	}
+}
+
+// initCenter brings a single cell to life, in the middle
+func (a *automata) initCenter(num int) {
+	a.cells = make([]int, num)
+	a.cells[num/2] = 1
+}
+
+// initBits sets the cells in the middle from a string of states,
+// one digit for each cell, like 101 for three cells on elementary rules
+func (a *automata) initBits(num int, bits string) error {
+	if len(bits) > num {
+		return fmt.Errorf("%d bits don't fit in %d cells", len(bits), num)
+	}
+
+	a.cells = make([]int, num)
+	offset := (num - len(bits)) / 2
+	for i, bit := range bits {
+		state := int(bit - '0')
+		if state < 0 || state >= a.rule.states {
+			return fmt.Errorf("%q isn't a state of the rule", bit)
+		}
+		a.cells[offset+i] = state
+	}
+	return nil
+}
+
+// initImage samples the pixels along a row of the image, the given
+// fraction of the way down, with the brightest ones in the highest state
+func (a *automata) initImage(num int, img image.Image, row float64) {
+	a.cells = make([]int, num)
+	bounds := img.Bounds()
+	y := bounds.Min.Y + int(row*float64(bounds.Dy()-1))
+	for i := range a.cells {
+		x := bounds.Min.X + i*bounds.Dx()/num
+		gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+		a.cells[i] = int(gray.Y) * a.rule.states / 256
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fogleman/gg"
+)
+
+// lifeRules holds the best known Life-like rules by name, in B/S notation
+var lifeRules = map[string]string{
+	"life":       "B3/S23",
+	"highlife":   "B36/S23",      // Like life, with replicators
+	"seeds":      "B2/S",         // Every cell dies right away, and still it explodes
+	"daynight":   "B3678/S34678", // Dead and live cells behave the same
+	"replicator": "B1357/S1357",  // Every pattern copies itself over and over
+	"maze":       "B3/S12345",    // Grows into mazes
+	"diamoeba":   "B35678/S5678", // Large diamonds with chaotic edges
+	"coral":      "B3/S45678",    // Slowly growing coral
+	"anneal":     "B4678/S35678", // Blobs that smooth out their edges
+	"morley":     "B368/S245",    // Lots of small spaceships
+}
+
+// patterns holds some well known patterns, in RLE format
+var patterns = map[string]string{
+	"glider":     "x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!",
+	"rpentomino": "x = 3, y = 3, rule = B3/S23\nb2o$2o$bo!",
+	"gosper": "x = 36, y = 9, rule = B3/S23\n" +
+		"24bo$22bobo$12b2o6b2o12b2o$11bo3bo4b2o12b2o$2o8bo5bo3b2o$" +
+		"2o8bo3bob2o4bobo$10bo5bo7bo$11bo3bo$12b2o!",
+	"replicator": "x = 5, y = 5, rule = B36/S23\n2b3o$bo2bo$o3bo$o2bo$3o!",
+}
+
+// lifeRule is a Life-like rule, telling for every number
+// of live neighbours if a dead cell is born, and if a live one survives
+type lifeRule struct {
+	born     [9]bool
+	survives [9]bool
+}
+
+// parseLifeRule reads a rule in B/S notation, like B3/S23 for Life
+func parseLifeRule(text string) (lifeRule, error) {
+	r := lifeRule{}
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(text)), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return r, fmt.Errorf("rule %q isn't in B/S notation", text)
+	}
+
+	for i, counts := range []*[9]bool{&r.born, &r.survives} {
+		for _, digit := range parts[i][1:] {
+			if digit < '0' || digit > '8' {
+				return r, fmt.Errorf("rule %q has a count of %q neighbours", text, digit)
+			}
+			counts[digit-'0'] = true
+		}
+	}
+	return r, nil
+}
+
+// pattern is a shape of live cells, and the rule it is meant to run with
+type pattern struct {
+	width, height int
+	cells         [][]bool // Rows of cells
+	rule          string
+}
+
+// parseRLE reads a pattern in the run length encoded format
+// used by most Life software, with an optional header line
+func parseRLE(text string) (pattern, error) {
+	p := pattern{}
+	body := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			// Comments and blank lines
+		case strings.HasPrefix(line, "x"):
+			for _, field := range strings.Split(line, ",") {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 {
+					return p, fmt.Errorf("bad header field %q", field)
+				}
+				value := strings.TrimSpace(kv[1])
+				switch strings.TrimSpace(kv[0]) {
+				case "x":
+					p.width, _ = strconv.Atoi(value)
+				case "y":
+					p.height, _ = strconv.Atoi(value)
+				case "rule":
+					p.rule = value
+				}
+			}
+		default:
+			body += line
+		}
+	}
+
+	row := []bool{}
+	count := 0
+	for _, c := range body {
+		if unicode.IsDigit(c) {
+			count = count*10 + int(c-'0')
+			continue
+		}
+		if count == 0 {
+			count = 1
+		}
+
+		switch c {
+		case 'b', 'o':
+			for i := 0; i < count; i++ {
+				row = append(row, c == 'o')
+			}
+		case '$', '!':
+			p.cells = append(p.cells, row)
+			row = []bool{}
+			// Several ends of line in a row skip empty rows
+			for i := 1; i < count && c == '$'; i++ {
+				p.cells = append(p.cells, row)
+			}
+		default:
+			return p, fmt.Errorf("unexpected %q in pattern", c)
+		}
+		count = 0
+
+		if c == '!' {
+			break
+		}
+	}
+
+	// The header is optional, so the pattern itself has the final say
+	p.height = len(p.cells)
+	for _, row := range p.cells {
+		if len(row) > p.width {
+			p.width = len(row)
+		}
+	}
+	return p, nil
+}
+
+// life is a two dimensional automaton, where every cell
+// lives or dies by the number of its live neighbours
+type life struct {
+	width, height int
+	ages          []int // How many generations every cell has lived for, 0 when dead
+	rule          lifeRule
+	toroidal      bool // Wrap around the edges, instead of having nothing past them
+}
+
+func newLife(width, height int, rule lifeRule, toroidal bool) *life {
+	return &life{
+		width:    width,
+		height:   height,
+		ages:     make([]int, width*height),
+		rule:     rule,
+		toroidal: toroidal,
+	}
+}
+
+// alive is true when the cell at x, y lives
+func (l *life) alive(x, y int) bool {
+	if l.toroidal {
+		x = (x + l.width) % l.width
+		y = (y + l.height) % l.height
+	} else if x < 0 || x >= l.width || y < 0 || y >= l.height {
+		return false
+	}
+	return l.ages[y*l.width+x] > 0
+}
+
+func (l *life) advance() {
+	newAges := make([]int, len(l.ages))
+	for y := 0; y < l.height; y++ {
+		for x := 0; x < l.width; x++ {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && l.alive(x+dx, y+dy) {
+						neighbours++
+					}
+				}
+			}
+
+			i := y*l.width + x
+			if l.ages[i] > 0 && l.rule.survives[neighbours] {
+				newAges[i] = l.ages[i] + 1
+			} else if l.ages[i] == 0 && l.rule.born[neighbours] {
+				newAges[i] = 1
+			}
+		}
+	}
+	l.ages = newAges
+}
+
+func (l *life) initRandom(density float64) {
+	for i := range l.ages {
+		l.ages[i] = 0
+		if rand.Float64() < density {
+			l.ages[i] = 1
+		}
+	}
+}
+
+// place brings the pattern to life, with its top left corner at x, y
+func (l *life) place(p pattern, x, y int) {
+	for dy, row := range p.cells {
+		for dx, live := range row {
+			if live && x+dx >= 0 && x+dx < l.width && y+dy >= 0 && y+dy < l.height {
+				l.ages[(y+dy)*l.width+x+dx] = 1
+			}
+		}
+	}
+}
+
+// draw draws the live cells, coloured by their age:
+// the first colour for newborn cells, the last one for the oldest
+func (l *life) draw(dc *gg.Context, size float64, colors []color.Color) {
+	for y := 0; y < l.height; y++ {
+		for x := 0; x < l.width; x++ {
+			age := l.ages[y*l.width+x]
+			if age == 0 {
+				continue
+			}
+			if age > len(colors) {
+				age = len(colors)
+			}
+			dc.SetColor(colors[age-1])
+			dc.DrawRectangle(float64(x)*size, float64(y)*size, size, size)
+			dc.Fill()
+		}
+	}
+}
+
+// animateLife evolves a square two dimensional automaton from a pattern,
+// or random cells, with every generation as a frame
+func animateLife(sink frameSink, cells int, ruleText, patternName string, density float64, toroidal bool, generations int, size float64, background color.Color, colors []color.Color) error {
+	if len(colors) == 0 {
+		return fmt.Errorf("live cells need at least one colour")
+	}
+
+	p := pattern{}
+	if patternName != "" {
+		text, ok := patterns[patternName]
+		if !ok {
+			data, err := os.ReadFile(patternName)
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+
+		var err error
+		if p, err = parseRLE(text); err != nil {
+			return err
+		}
+		// Patterns know best which rule they're made for
+		if p.rule != "" {
+			ruleText = p.rule
+		}
+	}
+
+	if named, ok := lifeRules[ruleText]; ok {
+		ruleText = named
+	}
+	rule, err := parseLifeRule(ruleText)
+	if err != nil {
+		return err
+	}
+
+	l := newLife(cells, cells, rule, toroidal)
+	if patternName != "" {
+		l.place(p, (cells-p.width)/2, (cells-p.height)/2)
+	} else {
+		l.initRandom(density)
+	}
+
+	for i := 0; i < generations; i++ {
+		dc := gg.NewContext(int(float64(cells)*size), int(float64(cells)*size))
+		dc.SetColor(background)
+		dc.Clear()
+		l.draw(dc, size, colors)
+
+		if err := sink.add(dc.Image()); err != nil {
+			return err
+		}
+		l.advance()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// drawn shows rows of cells with a # for every live one
+func drawn(cells [][]bool) string {
+	rows := []string{}
+	for _, row := range cells {
+		text := ""
+		for _, live := range row {
+			text += map[bool]string{true: "#", false: "."}[live]
+		}
+		rows = append(rows, text)
+	}
+	return strings.Join(rows, "/")
+}
+
+func TestParseRLE(t *testing.T) {
+	for _, c := range []struct {
+		name, text string
+		want, rule string
+	}{
+		{"glider", patterns["glider"], ".#/..#/###", "B3/S23"},
+		{"no header", "2o$obo!", "##/#.#", ""},
+		// Two ends of line leave an empty row between
+		{"empty row", "o2$bo!", "#//.#", ""},
+		{"runs", "3b12o!", "...############", ""},
+		{"comments", "#N blinker\n#C period 2\nx = 3, y = 1\n3o!", "###", ""},
+		{"split lines", "x = 2, y = 2\nbo$\no!", ".#/#", ""},
+		{"after the end", "o!bo$o", "#", ""},
+	} {
+		p, err := parseRLE(c.text)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got := drawn(p.cells); got != c.want || p.rule != c.rule {
+			t.Errorf("%s: cells %s and rule %q, want %s and %q", c.name, got, p.rule, c.want, c.rule)
+		}
+	}
+
+	p, _ := parseRLE(patterns["glider"])
+	if p.width != 3 || p.height != 3 {
+		t.Errorf("glider is %d by %d, want 3 by 3", p.width, p.height)
+	}
+	// The cells win over the header
+	if p, _ := parseRLE("x = 1, y = 1\n4o$o!"); p.width != 4 || p.height != 2 {
+		t.Errorf("pattern is %d by %d, want 4 by 2", p.width, p.height)
+	}
+
+	for _, text := range []string{"3x!", "x = 3, y\no!"} {
+		if _, err := parseRLE(text); err == nil {
+			t.Errorf("parseRLE(%q) gave no error", text)
+		}
+	}
+}
+
+func TestParseLifeRule(t *testing.T) {
+	for _, c := range []struct {
+		text           string
+		born, survives []int
+	}{
+		{"B3/S23", []int{3}, []int{2, 3}},
+		{"b36/s23", []int{3, 6}, []int{2, 3}},
+		{" B2/S ", []int{2}, nil},
+		{"B/S012345678", nil, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+	} {
+		r, err := parseLifeRule(c.text)
+		if err != nil {
+			t.Errorf("%q: %v", c.text, err)
+			continue
+		}
+		want := lifeRule{}
+		for _, n := range c.born {
+			want.born[n] = true
+		}
+		for _, n := range c.survives {
+			want.survives[n] = true
+		}
+		if r != want {
+			t.Errorf("%q = %+v, want %+v", c.text, r, want)
+		}
+	}
+
+	for _, text := range []string{"B9/S", "23/3", "B3", "B3/S2/S3", "S23/B3", "B3a/S23", ""} {
+		if _, err := parseLifeRule(text); err == nil {
+			t.Errorf("parseLifeRule(%q) gave no error", text)
+		}
+	}
+}
+
+// live shows the board with a # for every live cell
+func live(l *life) string {
+	cells := make([][]bool, l.height)
+	for y := range cells {
+		for x := 0; x < l.width; x++ {
+			cells[y] = append(cells[y], l.ages[y*l.width+x] > 0)
+		}
+	}
+	return drawn(cells)
+}
+
+func TestBlinker(t *testing.T) {
+	rule, _ := parseLifeRule("B3/S23")
+
+	for _, c := range []struct {
+		name     string
+		toroidal bool
+		x, y     int
+	}{
+		{"bounded", false, 1, 2},
+		{"toroidal", true, 1, 2},
+		// Right across the edges, on both sides of the board
+		{"toroidal, over the edge", true, -1, 0},
+	} {
+		l := newLife(5, 5, rule, c.toroidal)
+		for dx := 0; dx < 3; dx++ {
+			x := (c.x + dx + 5) % 5
+			l.ages[c.y*5+x] = 1
+		}
+		start := live(l)
+
+		l.advance()
+		once := live(l)
+		if strings.Count(once, "#") != 3 || once == start {
+			t.Errorf("%s: %v turned into %v, want it standing up", c.name, start, once)
+		}
+		l.advance()
+		if twice := live(l); twice != start {
+			t.Errorf("%s: %v turned into %v after two generations, want it back", c.name, start, twice)
+		}
+
+		// The middle cell lives on and on
+		if age := l.ages[c.y*5+(c.x+1+5)%5]; age != 3 {
+			t.Errorf("%s: middle cell %d generations old, want 3", c.name, age)
+		}
+	}
+
+	// Along the edge of a bounded board, half of it falls off
+	row, _ := parseRLE("3o!")
+	l := newLife(5, 5, rule, false)
+	l.place(row, 1, 0)
+	l.advance()
+	if got, want := live(l), "..#../..#../...../...../....."; got != want {
+		t.Errorf("blinker along the edge turned into %v, want %v", got, want)
+	}
+}
+
+// frames counts the frames it's given
+type frames struct{ count int }
+
+func (f *frames) add(img image.Image) error {
+	f.count++
+	return nil
+}
+
+func TestAnimateLifeNeedsColours(t *testing.T) {
+	f := &frames{}
+	if err := animateLife(f, 10, "life", "glider", 0, true, 3, 2, color.Black, nil); err == nil {
+		t.Error("no colours for the live cells, and no error")
+	}
+	if err := animateLife(f, 10, "life", "glider", 0, true, 3, 2, color.Black, []color.Color{color.White}); err != nil || f.count != 3 {
+		t.Errorf("%d frames with error %v, want 3 and none", f.count, err)
+	}
+}
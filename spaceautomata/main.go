@@ -2,54 +2,49 @@ package main
 
 import (
 	"image/color"
+	"log"
+	"log/slog"
 	"math/rand"
 	"time"
 
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/gg"
 )
 
 type automata struct {
-	cells  []bool
-	rule   map[[3]bool]bool
-	colors []color.Color
+	cells    []int // State of every cell, from 0 (empty) to the rule's states
+	rule     rule
+	boundary boundary      // What lies past the edges
+	colors   []color.Color // Colour of every state but the empty one
+
+	next []int   // Reused for every generation
+	fast *packed // Elementary rules evolve packed, once they've started
 }
 
 func (a *automata) advance() {
-	newCells := make([]bool, len(a.cells))
-	for i := 0; i < len(a.cells); i++ {
-		prev := i - 1
-		next := i + 1
-		if next == len(a.cells) {
-			next = 0
-		}
-		if prev == -1 {
-			prev = len(a.cells) - 1
+	if a.fast == nil {
+		if number, ok := a.rule.elementary(); ok {
+			a.fast = newPacked(a.cells, number, a.boundary)
 		}
-		newCells[i] = a.rule[[3]bool{
-			a.cells[prev],
-			a.cells[i],
-			a.cells[next],
-		}]
 	}
-	a.cells = newCells
-}
-
-func (a *automata) initRandom(num int) {
-	rand.Seed(time.Hour.Microseconds())
-	a.cells = make([]bool, num)
-	for i := 0; i < num; i++ {
-		a.cells[i] = rand.Float64() < 0.5
+	if a.fast != nil {
+		a.fast.advance()
+		a.fast.unpack(a.cells)
+		return
 	}
-}
 
-func (a *automata) draw(dc *gg.Context, y int, size float64) {
+	if len(a.next) != len(a.cells) {
+		a.next = make([]int, len(a.cells))
+	}
+	newCells := a.next
+	neighbourhood := make([]int, 2*a.rule.radius+1)
 	for i := 0; i < len(a.cells); i++ {
-		if a.cells[i] {
-			dc.SetColor(a.colors[rand.Intn(len(a.colors))])
-			dc.DrawRectangle(float64(i)*size, float64(y), size, size)
-			dc.Fill()
+		for j := range neighbourhood {
+			neighbourhood[j] = a.at(i + j - a.rule.radius)
 		}
+		newCells[i] = a.rule.apply(neighbourhood)
 	}
+	a.cells, a.next = newCells, a.cells
 }
 
 func main() {
@@ -59,18 +54,52 @@ func main() {
 		padding      = s / 20
 		chunkSize    = 20
 		borderRadius = padding * 1.5
+		ruleName     = "stripes" // Any rule from the catalogue
+
+		// One of rows, for a single automaton evolving down the image,
+		// gallery, for a tile for each of the gallery rules,
+		// scroll, for an animation of the rows scrolling up,
+		// or life, for an animation of a two dimensional automaton
+		mode = "rows"
+
+		// Where the automaton starts from: random, center for a single
+		// live cell, bits for the states below or image for a row of pixels
+		start     = "random"
+		density   = 0.5       // Of the random cells
+		bits      = "1011001" // States of the cells in the middle
+		imagePath = "input.png"
+		imageRow  = 0.5    // How far down the image the row of pixels is
+		edges     = "wrap" // Past the edges: wrap, fixed-0, fixed-1 or reflect
+
+		// How the cells are drawn: squares, circles, rounded, truchet or blobs
+		cellStyle = "squares"
+		polar     = false // Draw generations as rings around the center, instead of rows
+
+		// Scroll mode
+		scrollGenerations = 200 // Generations scrolling in
+		scrollSteps       = 4   // Frames per generation
+		morph             = false
+		morphFrom         = 90 // Rule numbers to morph between
+		morphTo           = 110
+
+		// Life mode
+		lifeRule    = "life" // Any rule from lifeRules, or one in B/S notation
+		lifePattern = ""     // Any pattern from patterns, or a path to an RLE file; random cells when empty
+		lifeCell    = 10     // Size of every cell
+		lifeDensity = 0.3    // Of the random cells
+		frames      = 300    // Frames in the animation
+		toroidal    = true   // Wrap around the edges
+
+		logLevel = slog.LevelInfo // slog.LevelWarn to leave out the progress
 	)
+	report.Log(logLevel)
+	// Wolfram numbers of the rules in the gallery, none for all 256
+	galleryRules := []uint8{30, 45, 54, 73, 90, 106, 110, 150, 184}
 
 	rand.Seed(time.Hour.Microseconds())
-	rule106 := map[[3]bool]bool{
-		{true, true, true}:    false,
-		{true, true, false}:   true,
-		{true, false, true}:   true,
-		{true, false, false}:  false,
-		{false, true, true}:   true,
-		{false, true, false}:  false,
-		{false, false, true}:  true,
-		{false, false, false}: false,
+	rule, err := namedRule(ruleName)
+	if err != nil {
+		log.Fatal(err)
 	}
 	clrs := []color.Color{
 		color.RGBA{68, 240, 210, 255},
@@ -78,23 +107,101 @@ func main() {
 		color.RGBA{38, 39, 112, 255},
 		color.RGBA{35, 5, 69, 255},
 	}
-	auto := &automata{rule: rule106, colors: clrs}
-	auto.initRandom(s / chunkSize)
+	bound, ok := boundaries[edges]
+	if !ok {
+		log.Fatalf("no boundary named %q", edges)
+	}
+	auto := &automata{rule: rule, boundary: bound, colors: clrs}
+	switch start {
+	case "center":
+		auto.initCenter(s / chunkSize)
+	case "bits":
+		if err := auto.initBits(s/chunkSize, bits); err != nil {
+			log.Fatal(err)
+		}
+	case "image":
+		img, err := gg.LoadImage(imagePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		auto.initImage(s/chunkSize, img, imageRow)
+	default:
+		auto.initRandom(s/chunkSize, density)
+	}
 
 	dc := gg.NewContext(int(s), int(s))
 
 	// Set a background color
-	dc.SetColor(color.RGBA{28, 0, 33, 255})
+	background := color.RGBA{28, 0, 33, 255}
+	dc.SetColor(background)
 	dc.Clear()
 
-	dc.DrawRoundedRectangle(padding, padding, s-padding*2, s-padding*2, borderRadius)
-	dc.Clip()
+	style, ok := renderStyles[cellStyle]
+	if !ok {
+		log.Fatalf("no cell style named %q", cellStyle)
+	}
+
+	// Animations save every frame as a numbered file
+	sink := &pngSequence{pattern: "i-%d.png"}
+
+	if mode == "scroll" {
+		sc := scroll{
+			size:        s,
+			cell:        chunkSize,
+			generations: scrollGenerations,
+			steps:       scrollSteps,
+			morph:       morph,
+			from:        morphFrom,
+			to:          morphTo,
+			style:       style,
+			background:  background,
+			clip: func(dc *gg.Context) {
+				dc.DrawRoundedRectangle(padding, padding, s-padding*2, s-padding*2, borderRadius)
+				dc.Clip()
+			},
+		}
+		if err := sc.animate(auto, reporting{sink, report.NewProgress(scrollGenerations * scrollSteps)}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if mode == "life" {
+		if err := animateLife(reporting{sink, report.NewProgress(frames)}, int(s/lifeCell), lifeRule, lifePattern, lifeDensity, toroidal, frames, lifeCell, background, clrs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if mode == "gallery" {
+		drawGallery(dc, galleryRules, auto, background, clrs[0])
+		if err := dc.SavePNG("output.png"); err != nil {
+			log.Fatal(err)
+		}
+		slog.Info("saved", "path", "output.png")
+		return
+	}
+
+	var l layout = rowsLayout{chunkSize}
+	generations := int(s / chunkSize)
+	if polar {
+		rings := newPolarLayout(s/2, s/2, chunkSize, len(auto.cells))
+		l, generations = rings, rings.generations(s/2-padding)
+	} else {
+		dc.DrawRoundedRectangle(padding, padding, s-padding*2, s-padding*2, borderRadius)
+		dc.Clip()
+	}
 
-	for i := 0; i < s; i += chunkSize {
-		auto.draw(dc, i, float64(chunkSize))
+	var above []int
+	for g := 0; g < generations; g++ {
+		auto.drawGeneration(dc, l, style, g, above)
+		above = append(above[:0], auto.cells...)
 		auto.advance()
 	}
 
 	// Save the output
-	dc.SavePNG("output.png")
+	if err := dc.SavePNG("output.png"); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("saved", "path", "output.png")
 }
@@ -0,0 +1,115 @@
+package main
+
+// packed is an elementary automaton with its cells packed
+// 64 to a word, evolving a whole word of cells at a time.
+// Cell i is bit i%64 of word i/64.
+type packed struct {
+	words    []uint64
+	next     []uint64 // Reused for every generation
+	width    int
+	number   uint8 // Wolfram number of the rule
+	boundary boundary
+}
+
+func newPacked(cells []int, number uint8, b boundary) *packed {
+	p := &packed{
+		words:    make([]uint64, (len(cells)+63)/64),
+		next:     make([]uint64, (len(cells)+63)/64),
+		width:    len(cells),
+		number:   number,
+		boundary: b,
+	}
+	for i, state := range cells {
+		if state > 0 {
+			p.words[i/64] |= 1 << (i % 64)
+		}
+	}
+	return p
+}
+
+// elementary returns the Wolfram number of the rule,
+// if it's an elementary one that can be packed
+func (r rule) elementary() (uint8, bool) {
+	if r.kind != general || r.states != 2 || r.radius != 1 {
+		return 0, false
+	}
+
+	number := uint8(0)
+	for n, state := range r.table {
+		number |= uint8(state) << n
+	}
+	return number, true
+}
+
+func (p *packed) bit(i int) uint64 {
+	return p.words[i/64] >> (i % 64) & 1
+}
+
+// ghosts returns the cells just past the left and right edges
+func (p *packed) ghosts() (uint64, uint64) {
+	switch p.boundary {
+	case fixedDead:
+		return 0, 0
+	case fixedLive:
+		return 1, 1
+	case reflect:
+		return p.bit(0), p.bit(p.width - 1)
+	default:
+		return p.bit(p.width - 1), p.bit(0)
+	}
+}
+
+func (p *packed) advance() {
+	if p.width == 0 {
+		return
+	}
+
+	left, right := p.ghosts()
+	last := len(p.words) - 1
+	for w, c := range p.words {
+		// Line up every cell's left and right neighbours with it,
+		// carrying them over from the words on either side
+		l := c<<1 | left
+		if w > 0 {
+			l = c<<1 | p.words[w-1]>>63
+		}
+		r := c>>1 | right<<((p.width-1)%64)
+		if w < last {
+			r = c>>1 | p.words[w+1]<<63
+		}
+
+		// Every neighbourhood that leads to a live cell
+		// adds the cells where it shows up
+		out := uint64(0)
+		for n := uint(0); n < 8; n++ {
+			if p.number&(1<<n) == 0 {
+				continue
+			}
+			ml, mc, mr := l, c, r
+			if n&4 == 0 {
+				ml = ^l
+			}
+			if n&2 == 0 {
+				mc = ^c
+			}
+			if n&1 == 0 {
+				mr = ^r
+			}
+			out |= ml & mc & mr
+		}
+		p.next[w] = out
+	}
+
+	// Clear the bits past the last cell
+	if p.width%64 != 0 {
+		p.next[last] &= 1<<(p.width%64) - 1
+	}
+	p.words, p.next = p.next, p.words
+}
+
+// unpack copies the cells into the given slice
+func (p *packed) unpack(cells []int) {
+	for i := range cells {
+		cells[i] = int(p.bit(i))
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// referenceRule builds an elementary rule the way the first version of
+// the sketch did, with a next state for every neighbourhood in a map
+func referenceRule(number uint8) map[[3]bool]bool {
+	rule := map[[3]bool]bool{}
+	for n := 0; n < 8; n++ {
+		rule[[3]bool{n&4 != 0, n&2 != 0, n&1 != 0}] = number&(1<<n) != 0
+	}
+	return rule
+}
+
+// referenceAdvance evolves the cells a generation, one cell at a time
+func referenceAdvance(cells []bool, rule map[[3]bool]bool, b boundary) []bool {
+	at := func(i int) bool {
+		n := len(cells)
+		switch {
+		case i >= 0 && i < n:
+			return cells[i]
+		case b == fixedDead:
+			return false
+		case b == fixedLive:
+			return true
+		case b == reflect && i < 0:
+			return cells[0]
+		case b == reflect:
+			return cells[n-1]
+		default:
+			return cells[(i+n)%n]
+		}
+	}
+
+	newCells := make([]bool, len(cells))
+	for i := range cells {
+		newCells[i] = rule[[3]bool{at(i - 1), at(i), at(i + 1)}]
+	}
+	return newCells
+}
+
+func randomCells(width int) ([]bool, []int) {
+	cells, states := make([]bool, width), make([]int, width)
+	for i := range cells {
+		cells[i] = rand.Intn(2) == 1
+		if cells[i] {
+			states[i] = 1
+		}
+	}
+	return cells, states
+}
+
+func TestPackedMatchesReference(t *testing.T) {
+	rand.Seed(1)
+	const generations = 40
+
+	for number := 0; number < 256; number++ {
+		rule := referenceRule(uint8(number))
+		for _, b := range []boundary{wrap, fixedDead, fixedLive, reflect} {
+			// Widths around the edges of the words
+			for _, width := range []int{1, 2, 63, 64, 65, 130} {
+				cells, states := randomCells(width)
+				p := newPacked(states, uint8(number), b)
+
+				for g := 0; g < generations; g++ {
+					cells = referenceAdvance(cells, rule, b)
+					p.advance()
+					p.unpack(states)
+
+					for i := range cells {
+						if cells[i] != (states[i] == 1) {
+							t.Fatalf("rule %d, boundary %d, width %d: cell %d differs at generation %d", number, b, width, i, g+1)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAutomataMatchesReference(t *testing.T) {
+	rand.Seed(2)
+
+	// Both the packed and the generic evolution, through the rule table
+	for number := 0; number < 256; number++ {
+		rule := referenceRule(uint8(number))
+		cells, states := randomCells(100)
+		packed := &automata{rule: wolframRule(uint8(number)), cells: states}
+		generic := &automata{rule: wolframRule(uint8(number)), cells: append([]int{}, states...)}
+		// Elementary rules written out as general ones with a
+		// wider radius don't get packed
+		generic.rule = widen(generic.rule)
+
+		for g := 0; g < 20; g++ {
+			cells = referenceAdvance(cells, rule, wrap)
+			packed.advance()
+			generic.advance()
+
+			for i := range cells {
+				if cells[i] != (packed.cells[i] == 1) || cells[i] != (generic.cells[i] == 1) {
+					t.Fatalf("rule %d: cell %d differs at generation %d", number, i, g+1)
+				}
+			}
+		}
+	}
+}
+
+// widen rewrites an elementary rule as a radius 2 rule
+// that ignores the outermost cells
+func widen(r rule) rule {
+	wide := rule{kind: general, states: 2, radius: 2}
+	wide.table = make([]int, wide.size())
+	for n := range wide.table {
+		wide.table[n] = r.table[n>>1&7]
+	}
+	return wide
+}
+
+func BenchmarkPacked(b *testing.B) {
+	_, states := randomCells(20000)
+	p := newPacked(states, 110, wrap)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.advance()
+	}
+}
+
+func BenchmarkReference(b *testing.B) {
+	cells, _ := randomCells(20000)
+	rule := referenceRule(110)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cells = referenceAdvance(cells, rule, wrap)
+	}
+}
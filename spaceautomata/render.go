@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// renderStyle is the shape every live cell is drawn as
+type renderStyle int
+
+const (
+	squares renderStyle = iota
+	circles
+	rounded
+	truchet // Diagonals, turned by the cells on either side
+	blobs   // Circles joined up with the live cells next to them
+)
+
+var renderStyles = map[string]renderStyle{
+	"squares": squares,
+	"circles": circles,
+	"rounded": rounded,
+	"truchet": truchet,
+	"blobs":   blobs,
+}
+
+// layout places the cells of every generation on the canvas
+type layout interface {
+	// frame moves the context to the middle of cell i of the given
+	// generation, turned so that generations run downwards,
+	// and returns how wide and how high the cell is
+	frame(dc *gg.Context, i, generation int) (float64, float64)
+	// traceCell traces the whole of the cell, as it is on the canvas
+	traceCell(dc *gg.Context, i, generation int)
+}
+
+// rowsLayout draws every generation as a row, below the one before
+type rowsLayout struct {
+	size float64
+}
+
+func (l rowsLayout) frame(dc *gg.Context, i, generation int) (float64, float64) {
+	dc.Translate((float64(i)+0.5)*l.size, (float64(generation)+0.5)*l.size)
+	return l.size, l.size
+}
+
+func (l rowsLayout) traceCell(dc *gg.Context, i, generation int) {
+	dc.DrawRectangle(float64(i)*l.size, float64(generation)*l.size, l.size, l.size)
+}
+
+// polarLayout draws every generation as a ring, around the one before
+type polarLayout struct {
+	x, y  float64 // Center of the rings
+	inner float64 // Radius of the first ring
+	ring  float64 // How far apart the rings are
+	cells int     // Cells in every ring
+}
+
+// newPolarLayout fits the rings around a center, starting where
+// the first ring's cells are about as wide as they are high
+func newPolarLayout(x, y, ring float64, cells int) polarLayout {
+	return polarLayout{x: x, y: y, inner: float64(cells) * ring / (2 * math.Pi), ring: ring, cells: cells}
+}
+
+// generations is how many rings fit within the given radius
+func (l polarLayout) generations(radius float64) int {
+	return int((radius - l.inner) / l.ring)
+}
+
+func (l polarLayout) angles(i int) (float64, float64) {
+	step := 2 * math.Pi / float64(l.cells)
+	return float64(i) * step, float64(i+1) * step
+}
+
+func (l polarLayout) frame(dc *gg.Context, i, generation int) (float64, float64) {
+	from, to := l.angles(i)
+	angle := (from + to) / 2
+	radius := l.inner + (float64(generation)+0.5)*l.ring
+
+	sin, cos := math.Sincos(angle)
+	dc.Translate(l.x+radius*cos, l.y+radius*sin)
+	// Downwards on the cell points away from the center
+	dc.Rotate(angle - math.Pi/2)
+	return radius * (to - from), l.ring
+}
+
+func (l polarLayout) traceCell(dc *gg.Context, i, generation int) {
+	from, to := l.angles(i)
+	inner := l.inner + float64(generation)*l.ring
+
+	dc.NewSubPath()
+	dc.DrawArc(l.x, l.y, inner, from, to)
+	dc.DrawArc(l.x, l.y, inner+l.ring, to, from)
+	dc.ClosePath()
+}
+
+// drawGeneration draws the live cells of the given generation,
+// each of them in the colour of its state. The generation before,
+// if any, is where blobs join up with the cells above them.
+func (a *automata) drawGeneration(dc *gg.Context, l layout, style renderStyle, generation int, above []int) {
+	for i := 0; i < len(a.cells); i++ {
+		if a.cells[i] == 0 {
+			continue
+		}
+		dc.SetColor(a.colors[(a.cells[i]-1)%len(a.colors)])
+
+		if style == squares {
+			l.traceCell(dc, i, generation)
+			dc.Fill()
+			continue
+		}
+
+		dc.Push()
+		w, h := l.frame(dc, i, generation)
+		size := math.Min(w, h)
+
+		switch style {
+		case circles:
+			dc.DrawCircle(0, 0, size/2)
+			dc.Fill()
+		case rounded:
+			dc.DrawRoundedRectangle(-w/2, -h/2, w, h, size/4)
+			dc.Fill()
+		case truchet:
+			// Cells with the same on either side lean one way, the rest the other
+			if (a.at(i-1) > 0) == (a.at(i+1) > 0) {
+				dc.DrawLine(-w/2, -h/2, w/2, h/2)
+			} else {
+				dc.DrawLine(w/2, -h/2, -w/2, h/2)
+			}
+			dc.SetLineCapRound()
+			dc.SetLineWidth(size / 3)
+			dc.Stroke()
+		case blobs:
+			dc.DrawCircle(0, 0, size/2)
+			// Reach out to the next cell in the same generation,
+			// and to the one above in the generation before
+			if i < len(a.cells)-1 && a.cells[i+1] > 0 {
+				dc.DrawRectangle(0, -size/2, w, size)
+			}
+			if above != nil && above[i] > 0 {
+				dc.DrawRectangle(-size/2, -h, size, h)
+			}
+			dc.Fill()
+		}
+		dc.Pop()
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
+// ruleKind is how a rule reads the neighbourhood of a cell
+type ruleKind int
+
+const (
+	general         ruleKind = iota // Every neighbourhood has its own next state
+	totalistic                      // Only the sum of the neighbourhood counts
+	outerTotalistic                 // The cell's own state, and the sum of the ones around it
+)
+
+// rule decides the next state of a cell from its neighbourhood:
+// the cell itself and the ones within radius on either side
+type rule struct {
+	kind   ruleKind
+	states int
+	radius int
+	table  []int // Next state for every neighbourhood, by index
+}
+
+// newRule builds a rule from its Wolfram code, where digit n
+// of the code in base states is the next state for index n
+func newRule(kind ruleKind, states, radius int, code uint64) rule {
+	r := rule{kind: kind, states: states, radius: radius}
+	r.table = make([]int, r.size())
+	for n := range r.table {
+		r.table[n] = int(code % uint64(states))
+		code /= uint64(states)
+	}
+	return r
+}
+
+// wolframRule builds the rule an elementary automaton follows from its
+// Wolfram number, where bit n of the number is the next state of a cell
+// whose neighbourhood (left, self, right) reads n in binary
+func wolframRule(number uint8) rule {
+	return newRule(general, 2, 1, uint64(number))
+}
+
+// size is how many entries the rule's table has
+func (r rule) size() int {
+	width := 2*r.radius + 1
+	switch r.kind {
+	case totalistic:
+		return width*(r.states-1) + 1
+	case outerTotalistic:
+		return r.states * ((width-1)*(r.states-1) + 1)
+	default:
+		return int(math.Pow(float64(r.states), float64(width)))
+	}
+}
+
+// apply returns the next state of the cell in the middle of the neighbourhood
+func (r rule) apply(neighbourhood []int) int {
+	n := 0
+	switch r.kind {
+	case totalistic:
+		for _, state := range neighbourhood {
+			n += state
+		}
+	case outerTotalistic:
+		center := neighbourhood[r.radius]
+		for _, state := range neighbourhood {
+			n += state
+		}
+		n = center*((len(neighbourhood)-1)*(r.states-1)+1) + n - center
+	default:
+		// The neighbourhood reads as a number in base states,
+		// the leftmost cell being the most significant digit
+		for _, state := range neighbourhood {
+			n = n*r.states + state
+		}
+	}
+	return r.table[n]
+}
+
+// catalogue holds the best known rules by name
+var catalogue = map[string]rule{
+	"sierpinski": wolframRule(90),  // Nested triangles from a single cell
+	"chaos":      wolframRule(30),  // Random enough to be used as a generator
+	"universal":  wolframRule(110), // Turing complete
+	"traffic":    wolframRule(184), // Cars moving right, jamming up behind each other
+	"fractal":    wolframRule(150), // Sierpinski with a twist
+	"stripes":    wolframRule(106), // Diagonal stripes
+	"zigzag":     wolframRule(73),  // Walls with noise between them
+	"triangles":  wolframRule(18),  // Sparse triangles on a quiet background
+	"highway":    wolframRule(54),  // Particles bouncing off each other
+	"mirror":     wolframRule(45),  // Like chaos, mirrored
+
+	// Three colours, by the sum of the neighbourhood
+	"lace":    newRule(totalistic, 3, 1, 1599), // Long lived structures growing out of the noise
+	"bubbles": newRule(totalistic, 3, 1, 1635), // Nested triangles, three colours deep
+	"weave":   newRule(totalistic, 3, 1, 2049), // Triangles over a background of threads
+
+	// Two colours, two cells on either side
+	"lattice": newRule(outerTotalistic, 2, 2, 614), // Brickwork
+}
+
+// namedRule returns the rule with the given name from the catalogue
+func namedRule(name string) (rule, error) {
+	r, ok := catalogue[name]
+	if !ok {
+		return rule{}, fmt.Errorf("no rule named %q, try one of %v", name, catalogueNames())
+	}
+	return r, nil
+}
+
+// catalogueNames returns the names of the catalogue in a stable order
+func catalogueNames() []string {
+	names := []string{}
+	for name := range catalogue {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// drawGallery draws a tile for every one of the given rules, all of them
+// evolving from the same cells as the given automaton, labelled with their
+// numbers. No rules at all draws every one of the 256.
+func drawGallery(dc *gg.Context, numbers []uint8, from *automata, background, label color.Color) {
+	if len(numbers) == 0 {
+		for n := 0; n < 256; n++ {
+			numbers = append(numbers, uint8(n))
+		}
+	}
+
+	w, h := float64(dc.Width()), float64(dc.Height())
+	cols := int(math.Ceil(math.Sqrt(float64(len(numbers)))))
+	rows := (len(numbers) + cols - 1) / cols
+	tileW, tileH := w/float64(cols), h/float64(rows)
+	gap := math.Min(tileW, tileH) / 20
+	size := (tileW - gap*2) / float64(len(from.cells))
+
+	for i, number := range numbers {
+		left := float64(i%cols)*tileW + gap
+		top := float64(i/cols)*tileH + gap
+
+		auto := &automata{rule: wolframRule(number), boundary: from.boundary, colors: from.colors}
+		auto.cells = append([]int{}, from.cells...)
+
+		dc.Push()
+		dc.Translate(left, top)
+		dc.DrawRectangle(0, 0, tileW-gap*2, tileH-gap*2)
+		dc.Clip()
+		for g := 0; float64(g)*size < tileH-gap*2; g++ {
+			auto.drawGeneration(dc, rowsLayout{size}, squares, g, nil)
+			auto.advance()
+		}
+		dc.ResetClip()
+
+		// Labels sit on a patch of background, to stand out from the cells
+		text := fmt.Sprint(number)
+		textW, textH := dc.MeasureString(text)
+		dc.SetColor(background)
+		dc.DrawRectangle(0, 0, textW+4, textH+4)
+		dc.Fill()
+		dc.SetColor(label)
+		dc.DrawStringAnchored(text, 2, 2, 0, 1)
+		dc.Pop()
+	}
+}
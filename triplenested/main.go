@@ -3,9 +3,12 @@ package main
 import (
 	"fmt"
 	"image/color"
+	"log"
+	"log/slog"
 	"math/rand"
 	"time"
 
+	"github.com/dangelov/martegeno/report"
 	"github.com/fogleman/ease"
 	"github.com/fogleman/gg"
 )
@@ -23,7 +26,10 @@ func main() {
 		animationStep = 0.05
 
 		maxCircleRadius = s / 30
+
+		logLevel = slog.LevelInfo // slog.LevelWarn to leave out the progress
 	)
+	report.Log(logLevel)
 
 	rand.Seed(time.Hour.Microseconds())
 
@@ -54,6 +60,7 @@ func main() {
 	}
 
 	// Go through the times and draw the circles
+	progress := report.NewProgress(len(times))
 	for i := 0; i < len(times); i++ {
 
 		dc := gg.NewContext(int(s), int(s))
@@ -72,7 +79,10 @@ func main() {
 		}
 
 		// Save the output
-		dc.SavePNG(fmt.Sprintf("i-%d.png", i))
+		if err := dc.SavePNG(fmt.Sprintf("i-%d.png", i)); err != nil {
+			log.Fatal(err)
+		}
+		progress.Frame()
 	}
 }
 